@@ -0,0 +1,267 @@
+// Package adapter lets the handlers written for REST API events serve HTTP
+// API events and Lambda function URL ones, answering each in its own format
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/server"
+	"github.com/fpinatares/magneto/tracing"
+)
+
+const (
+	REST         = "rest"
+	HTTP_API     = "http"
+	FUNCTION_URL = "url"
+)
+
+// PAYLOAD_V2 is the version of the payload of HTTP APIs and function URLs
+const PAYLOAD_V2 = "2.0"
+
+const DEFAULT_STAGE = "$default"
+
+// shape has the fields that tell the events apart
+type shape struct {
+	Version        string `json:"version"`
+	RequestContext struct {
+		DomainName string `json:"domainName"`
+	} `json:"requestContext"`
+}
+
+// Kind is REST for API Gateway REST APIs, HTTP_API for its HTTP APIs and
+// FUNCTION_URL for function URLs, whose domain is <id>.lambda-url.<region>.on.aws
+func Kind(event json.RawMessage) (string, error) {
+	s := shape{}
+	err := json.Unmarshal(event, &s)
+	if err != nil {
+		return "", err
+	}
+	if s.Version != PAYLOAD_V2 {
+		return REST, nil
+	}
+	if strings.Contains(s.RequestContext.DomainName, ".lambda-url.") {
+		return FUNCTION_URL, nil
+	}
+	return HTTP_API, nil
+}
+
+// Handler is the function to start the lambda with. It translates the event
+// into a REST one for the handler, and the response back into the format of
+// the event. The spans of the request are exported before the lambda is frozen
+func Handler(next server.Handler) func(context.Context, json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		defer tracing.Flush(ctx)
+		handler := func(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			return next(WithInvocation(ctx, req))
+		}
+		kind, err := Kind(event)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case HTTP_API:
+			req := events.APIGatewayV2HTTPRequest{}
+			err = json.Unmarshal(event, &req)
+			if err != nil {
+				return nil, err
+			}
+			response, err := handler(FromHTTPAPI(req))
+			if err != nil {
+				return nil, err
+			}
+			return ToHTTPAPI(response), nil
+		case FUNCTION_URL:
+			req := events.LambdaFunctionURLRequest{}
+			err = json.Unmarshal(event, &req)
+			if err != nil {
+				return nil, err
+			}
+			response, err := handler(FromFunctionURL(req))
+			if err != nil {
+				return nil, err
+			}
+			return ToFunctionURL(response), nil
+		}
+		req := events.APIGatewayProxyRequest{}
+		err = json.Unmarshal(event, &req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(req)
+	}
+}
+
+// WithInvocation sets the id of the lambda request in the headers, for the
+// handlers to log with, dropping the one a client could have sent
+func WithInvocation(ctx context.Context, req events.APIGatewayProxyRequest) events.APIGatewayProxyRequest {
+	headers := map[string]string{}
+	for name, value := range req.Headers {
+		if !strings.EqualFold(name, logging.INVOCATION_ID_HEADER) {
+			headers[name] = value
+		}
+	}
+	multiValueHeaders := map[string][]string{}
+	for name, value := range req.MultiValueHeaders {
+		if !strings.EqualFold(name, logging.INVOCATION_ID_HEADER) {
+			multiValueHeaders[name] = value
+		}
+	}
+	if id := logging.InvocationId(ctx); id != "" {
+		headers[logging.INVOCATION_ID_HEADER] = id
+		multiValueHeaders[logging.INVOCATION_ID_HEADER] = []string{id}
+	}
+	req.Headers = headers
+	req.MultiValueHeaders = multiValueHeaders
+	return req
+}
+
+// FromHTTPAPI translates the request. The path drops the stage, which HTTP
+// APIs only leave out for the $default one
+func FromHTTPAPI(req events.APIGatewayV2HTTPRequest) events.APIGatewayProxyRequest {
+	path := req.RawPath
+	stage := req.RequestContext.Stage
+	if stage != "" && stage != DEFAULT_STAGE && strings.HasPrefix(path, "/"+stage+"/") {
+		path = strings.TrimPrefix(path, "/"+stage)
+	}
+	proxy := NewRequest(path, req.RequestContext.HTTP.Method, req.Headers, req.Cookies, req.RawQueryString, req.QueryStringParameters)
+	proxy.PathParameters = req.PathParameters
+	proxy.StageVariables = req.StageVariables
+	proxy.Body = req.Body
+	proxy.IsBase64Encoded = req.IsBase64Encoded
+	proxy.RequestContext = events.APIGatewayProxyRequestContext{
+		AccountID:  req.RequestContext.AccountID,
+		RequestID:  req.RequestContext.RequestID,
+		Stage:      stage,
+		DomainName: req.RequestContext.DomainName,
+		APIID:      req.RequestContext.APIID,
+		HTTPMethod: req.RequestContext.HTTP.Method,
+		Path:       req.RequestContext.HTTP.Path,
+		Identity: events.APIGatewayRequestIdentity{
+			SourceIP:  req.RequestContext.HTTP.SourceIP,
+			UserAgent: req.RequestContext.HTTP.UserAgent,
+		},
+	}
+	return proxy
+}
+
+func FromFunctionURL(req events.LambdaFunctionURLRequest) events.APIGatewayProxyRequest {
+	proxy := NewRequest(req.RawPath, req.RequestContext.HTTP.Method, req.Headers, req.Cookies, req.RawQueryString, req.QueryStringParameters)
+	proxy.Body = req.Body
+	proxy.IsBase64Encoded = req.IsBase64Encoded
+	proxy.RequestContext = events.APIGatewayProxyRequestContext{
+		AccountID:  req.RequestContext.AccountID,
+		RequestID:  req.RequestContext.RequestID,
+		DomainName: req.RequestContext.DomainName,
+		APIID:      req.RequestContext.APIID,
+		HTTPMethod: req.RequestContext.HTTP.Method,
+		Path:       req.RequestContext.HTTP.Path,
+		Identity: events.APIGatewayRequestIdentity{
+			SourceIP:  req.RequestContext.HTTP.SourceIP,
+			UserAgent: req.RequestContext.HTTP.UserAgent,
+		},
+	}
+	return proxy
+}
+
+// NewRequest fills the headers and the query parameters of a REST event from
+// the ones of a 2.0 payload, which joins the values of a header with commas
+// and sends the cookies apart
+func NewRequest(path string, method string, headers map[string]string, cookies []string, rawQuery string, query map[string]string) events.APIGatewayProxyRequest {
+	req := events.APIGatewayProxyRequest{
+		Resource:                        path,
+		Path:                            path,
+		HTTPMethod:                      method,
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string{},
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string{},
+	}
+	for name, value := range headers {
+		req.Headers[name] = value
+		req.MultiValueHeaders[name] = SplitHeader(value)
+	}
+	if len(cookies) > 0 {
+		req.Headers["cookie"] = strings.Join(cookies, "; ")
+		req.MultiValueHeaders["cookie"] = cookies
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil || len(values) == 0 {
+		for name, value := range query {
+			values[name] = strings.Split(value, ",")
+		}
+	}
+	for name, value := range values {
+		req.QueryStringParameters[name] = value[len(value)-1]
+		req.MultiValueQueryStringParameters[name] = value
+	}
+	return req
+}
+
+func SplitHeader(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		values = append(values, strings.TrimSpace(v))
+	}
+	return values
+}
+
+// ToHTTPAPI answers the Set-Cookie headers as cookies, since a 2.0 payload
+// response can not have the header more than once
+func ToHTTPAPI(response events.APIGatewayProxyResponse) events.APIGatewayV2HTTPResponse {
+	headers, cookies := MergeHeaders(response)
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode:      response.StatusCode,
+		Headers:         headers,
+		Body:            response.Body,
+		IsBase64Encoded: response.IsBase64Encoded,
+		Cookies:         cookies,
+	}
+}
+
+func ToFunctionURL(response events.APIGatewayProxyResponse) events.LambdaFunctionURLResponse {
+	headers, cookies := MergeHeaders(response)
+	return events.LambdaFunctionURLResponse{
+		StatusCode:      response.StatusCode,
+		Headers:         headers,
+		Body:            response.Body,
+		IsBase64Encoded: response.IsBase64Encoded,
+		Cookies:         cookies,
+	}
+}
+
+// MergeHeaders joins the values of every header with commas, like the
+// 2.0 payload expects, except for Set-Cookie, whose values are the cookies
+func MergeHeaders(response events.APIGatewayProxyResponse) (map[string]string, []string) {
+	values := map[string][]string{}
+	names := map[string]string{}
+	add := func(name string, value ...string) {
+		key := strings.ToLower(name)
+		if _, ok := names[key]; !ok {
+			names[key] = name
+		}
+		values[key] = append(values[key], value...)
+	}
+	for name, value := range response.Headers {
+		if _, ok := response.MultiValueHeaders[name]; !ok {
+			add(name, value)
+		}
+	}
+	for name, value := range response.MultiValueHeaders {
+		add(name, value...)
+	}
+	headers := map[string]string{}
+	cookies := []string{}
+	for key, value := range values {
+		if key == "set-cookie" {
+			cookies = append(cookies, value...)
+			continue
+		}
+		headers[names[key]] = strings.Join(value, ",")
+	}
+	return headers, cookies
+}
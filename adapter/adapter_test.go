@@ -0,0 +1,139 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/repository"
+	"github.com/fpinatares/magneto/stats"
+)
+
+var received events.APIGatewayProxyRequest
+
+func echo(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	received = req
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "text/plain"},
+		MultiValueHeaders: map[string][]string{
+			"Set-Cookie": {"a=1", "b=2"},
+			"Vary":       {"Accept", "Origin"},
+		},
+		Body: req.Path,
+	}, nil
+}
+
+const HTTP_API_EVENT = `{
+	"version": "2.0",
+	"routeKey": "$default",
+	"rawPath": "/prod/stats",
+	"rawQueryString": "group_by=size&a=1&a=2",
+	"cookies": ["session=1", "theme=dark"],
+	"headers": {"accept": "text/html, application/json", "content-type": "application/json"},
+	"requestContext": {
+		"stage": "prod",
+		"requestId": "request",
+		"domainName": "abc.execute-api.us-east-1.amazonaws.com",
+		"http": {"method": "GET", "path": "/prod/stats", "sourceIp": "1.2.3.4"}
+	},
+	"isBase64Encoded": false
+}`
+
+const FUNCTION_URL_EVENT = `{
+	"version": "2.0",
+	"rawPath": "/mutant",
+	"rawQueryString": "",
+	"headers": {"content-type": "application/json"},
+	"requestContext": {
+		"requestId": "request",
+		"domainName": "abc.lambda-url.us-east-1.on.aws",
+		"http": {"method": "POST", "path": "/mutant", "sourceIp": "1.2.3.4"}
+	},
+	"body": "{}",
+	"isBase64Encoded": false
+}`
+
+const REST_EVENT = `{"resource": "/stats", "path": "/stats", "httpMethod": "GET", "headers": {}}`
+
+func TestKind(t *testing.T) {
+	for event, kind := range map[string]string{HTTP_API_EVENT: HTTP_API, FUNCTION_URL_EVENT: FUNCTION_URL, REST_EVENT: REST} {
+		got, err := Kind(json.RawMessage(event))
+		if err != nil || got != kind {
+			t.Error("Expected kind", kind, "Got:", got, err)
+		}
+	}
+}
+
+func TestHandlerHTTPAPI(t *testing.T) {
+	output, err := Handler(echo)(context.Background(), json.RawMessage(HTTP_API_EVENT))
+	response, ok := output.(events.APIGatewayV2HTTPResponse)
+	if err != nil || !ok {
+		t.Fatal("Expected an HTTP API response. Got:", output, err)
+	}
+	if received.Path != "/stats" || received.HTTPMethod != "GET" || received.RequestContext.Identity.SourceIP != "1.2.3.4" {
+		t.Error("Expected the request without the stage. Got:", received)
+	}
+	if received.Headers["cookie"] != "session=1; theme=dark" || len(received.MultiValueHeaders["accept"]) != 2 {
+		t.Error("Expected the cookies and every header value. Got:", received.Headers, received.MultiValueHeaders)
+	}
+	if received.QueryStringParameters["a"] != "2" || len(received.MultiValueQueryStringParameters["a"]) != 2 || received.QueryStringParameters["group_by"] != "size" {
+		t.Error("Expected every query value. Got:", received.MultiValueQueryStringParameters)
+	}
+	sort.Strings(response.Cookies)
+	if response.StatusCode != 200 || len(response.Cookies) != 2 || response.Cookies[0] != "a=1" {
+		t.Error("Expected the Set-Cookie headers as cookies. Got:", response)
+	}
+	if response.Headers["Vary"] != "Accept,Origin" || response.Headers["Content-Type"] != "text/plain" || response.Headers["Set-Cookie"] != "" {
+		t.Error("Expected the headers joined by commas. Got:", response.Headers)
+	}
+}
+
+func TestHandlerFunctionURL(t *testing.T) {
+	output, err := Handler(echo)(context.Background(), json.RawMessage(FUNCTION_URL_EVENT))
+	response, ok := output.(events.LambdaFunctionURLResponse)
+	if err != nil || !ok {
+		t.Fatal("Expected a function URL response. Got:", output, err)
+	}
+	if received.Path != "/mutant" || received.HTTPMethod != "POST" || received.Body != "{}" || received.Headers["content-type"] != "application/json" {
+		t.Error("Expected the request. Got:", received)
+	}
+	if response.StatusCode != 200 || response.Body != "/mutant" || len(response.Cookies) != 2 {
+		t.Error("Expected the response. Got:", response)
+	}
+}
+
+func TestHandlerREST(t *testing.T) {
+	output, err := Handler(echo)(context.Background(), json.RawMessage(REST_EVENT))
+	response, ok := output.(events.APIGatewayProxyResponse)
+	if err != nil || !ok || response.Body != "/stats" || len(response.MultiValueHeaders["Set-Cookie"]) != 2 {
+		t.Error("Expected the REST response unchanged. Got:", output, err)
+	}
+}
+
+func TestHandlerGetStats(t *testing.T) {
+	d := stats.NewHandler(nil, repository.NewMemoryRepository(), nil, "")
+	output, err := Handler(d.GetStats)(context.Background(), json.RawMessage(`{
+		"version": "2.0",
+		"rawPath": "/stats",
+		"requestContext": {"stage": "$default", "domainName": "abc.execute-api.us-east-1.amazonaws.com", "http": {"method": "GET"}}
+	}`))
+	response, ok := output.(events.APIGatewayV2HTTPResponse)
+	if err != nil || !ok || response.StatusCode != 200 || response.Body != "{\"count_mutant_dna\":0,\"count_human_dna\":0,\"ratio\":0}" || response.Headers["ETag"] == "" {
+		t.Error("Expected the stats with their ETag. Got:", output, err)
+	}
+}
+
+func TestHandlerSetsTheInvocationId(t *testing.T) {
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "invocation"})
+	event := `{"resource": "/stats", "path": "/stats", "httpMethod": "GET", "headers": {"x-lambda-request-id": "spoofed"}}`
+	_, err := Handler(echo)(ctx, json.RawMessage(event))
+	if err != nil || len(received.Headers) != 1 || received.Headers[logging.INVOCATION_ID_HEADER] != "invocation" {
+		t.Error("Expected the id of the lambda request in the headers. Got:", received.Headers, err)
+	}
+}
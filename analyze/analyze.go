@@ -0,0 +1,338 @@
+// Package analyze runs the detector on dna files, without AWS, for the
+// magneto analyze command
+package analyze
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fpinatares/magneto/detector"
+)
+
+const (
+	FORMAT_AUTO  = "auto"
+	FORMAT_JSON  = "json"
+	FORMAT_FASTA = "fasta"
+	FORMAT_TEXT  = "text"
+)
+
+const (
+	OUTPUT_TABLE = "table"
+	OUTPUT_JSON  = "json"
+	OUTPUT_JSONL = "jsonl"
+)
+
+// The exit codes tell the shell whether every dna was human. Invalid input
+// takes precedence over mutants
+const (
+	EXIT_HUMAN   = 0
+	EXIT_MUTANT  = 1
+	EXIT_USAGE   = 2
+	EXIT_INVALID = 3
+)
+
+const INVALID = "Invalid"
+
+const STDIN = "-"
+
+// Input is a dna read from a file. The name is the FASTA header, or the
+// position of the dna in the file for the other formats
+type Input struct {
+	Source string
+	Name   string
+	Dna    []string
+}
+
+type Result struct {
+	Source  string   `json:"source"`
+	Name    string   `json:"name"`
+	Dna     []string `json:"dna"`
+	Verdict string   `json:"verdict"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Run analyzes the files of the arguments, or stdin when there are none, and
+// returns the exit code
+func Run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", FORMAT_AUTO, "input format: auto, json, fasta or text")
+	output := flags.String("output", OUTPUT_TABLE, "output format: table, json or jsonl")
+	err := flags.Parse(args)
+	if err != nil {
+		return EXIT_USAGE
+	}
+	if !IsFormat(*format) {
+		fmt.Fprintln(stderr, "unknown format", *format)
+		return EXIT_USAGE
+	}
+	if *output != OUTPUT_TABLE && *output != OUTPUT_JSON && *output != OUTPUT_JSONL {
+		fmt.Fprintln(stderr, "unknown output", *output)
+		return EXIT_USAGE
+	}
+	sources := flags.Args()
+	if len(sources) == 0 {
+		sources = []string{STDIN}
+	}
+	results := []Result{}
+	for _, source := range sources {
+		inputs, err := ReadSource(source, *format, stdin)
+		if err != nil {
+			results = append(results, Result{
+				Source:  source,
+				Verdict: INVALID,
+				Error:   err.Error(),
+			})
+			continue
+		}
+		for _, input := range inputs {
+			results = append(results, Analyze(input))
+		}
+	}
+	err = Write(stdout, results, *output)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return EXIT_USAGE
+	}
+	return ExitCode(results)
+}
+
+func IsFormat(format string) bool {
+	switch format {
+	case FORMAT_AUTO, FORMAT_JSON, FORMAT_FASTA, FORMAT_TEXT:
+		return true
+	}
+	return false
+}
+
+// Analyze validates the dna like the detector does, and also checks that it
+// is a square matrix, which IsMutant expects
+func Analyze(input Input) Result {
+	result := Result{
+		Source: input.Source,
+		Name:   input.Name,
+		Dna:    input.Dna,
+	}
+	err := detector.ValidateMatrix(input.Dna)
+	if err == nil {
+		err = detector.ValidateDna(input.Dna)
+	}
+	if err != nil {
+		result.Verdict = INVALID
+		result.Error = err.Error()
+		return result
+	}
+	result.Verdict = detector.GetDnaType(input.Dna)
+	return result
+}
+
+func ExitCode(results []Result) int {
+	code := EXIT_HUMAN
+	for _, result := range results {
+		if result.Verdict == INVALID {
+			return EXIT_INVALID
+		}
+		if result.Verdict == detector.EnumDnaType.Mutant {
+			code = EXIT_MUTANT
+		}
+	}
+	return code
+}
+
+func ReadSource(source string, format string, stdin io.Reader) ([]Input, error) {
+	var data []byte
+	var err error
+	if source == STDIN {
+		data, err = ioutil.ReadAll(stdin)
+	} else {
+		data, err = ioutil.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if format == FORMAT_AUTO {
+		format = DetectFormat(source, data)
+	}
+	switch format {
+	case FORMAT_JSON:
+		return ParseJSON(source, data)
+	case FORMAT_FASTA:
+		return ParseFASTA(source, data), nil
+	}
+	return ParseText(source, data), nil
+}
+
+// DetectFormat goes by the extension of the file, or else by its first
+// character
+func DetectFormat(source string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".json", ".jsonl":
+		return FORMAT_JSON
+	case ".fa", ".fasta", ".fna":
+		return FORMAT_FASTA
+	case ".txt":
+		return FORMAT_TEXT
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 {
+		switch trimmed[0] {
+		case '{', '[':
+			return FORMAT_JSON
+		case '>':
+			return FORMAT_FASTA
+		}
+	}
+	return FORMAT_TEXT
+}
+
+// ParseJSON reads the body the detector accepts, an array of them, an array
+// of sequences, or one of those per line
+func ParseJSON(source string, data []byte) ([]Input, error) {
+	inputs := []Input{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var value json.RawMessage
+		err := decoder.Decode(&value)
+		if err == io.EOF {
+			return inputs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		dnas, err := DecodeDnas(value)
+		if err != nil {
+			return nil, err
+		}
+		for _, dna := range dnas {
+			inputs = append(inputs, Input{
+				Source: source,
+				Name:   strconv.Itoa(len(inputs) + 1),
+				Dna:    dna,
+			})
+		}
+	}
+}
+
+func DecodeDnas(value json.RawMessage) ([][]string, error) {
+	body := detector.DnaData{}
+	if json.Unmarshal(value, &body) == nil {
+		return [][]string{body.Dna}, nil
+	}
+	sequences := []string{}
+	if json.Unmarshal(value, &sequences) == nil {
+		return [][]string{sequences}, nil
+	}
+	bodies := []detector.DnaData{}
+	err := json.Unmarshal(value, &bodies)
+	if err != nil {
+		return nil, errors.New("expected a dna, a list of dnas or a list of sequences")
+	}
+	dnas := [][]string{}
+	for _, body := range bodies {
+		dnas = append(dnas, body.Dna)
+	}
+	return dnas, nil
+}
+
+// ParseFASTA reads a dna per record, with a row per line under the header
+func ParseFASTA(source string, data []byte) []Input {
+	inputs := []Input{}
+	for _, line := range Lines(data) {
+		if strings.HasPrefix(line, ">") {
+			inputs = append(inputs, Input{
+				Source: source,
+				Name:   strings.TrimSpace(strings.TrimPrefix(line, ">")),
+				Dna:    []string{},
+			})
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if len(inputs) == 0 {
+			inputs = append(inputs, Input{Source: source, Name: "1", Dna: []string{}})
+		}
+		current := &inputs[len(inputs)-1]
+		current.Dna = append(current.Dna, line)
+	}
+	return inputs
+}
+
+// ParseText reads a row per line, with blank lines between the dnas and #
+// starting comments
+func ParseText(source string, data []byte) []Input {
+	inputs := []Input{}
+	dna := []string{}
+	flush := func() {
+		if len(dna) > 0 {
+			inputs = append(inputs, Input{
+				Source: source,
+				Name:   strconv.Itoa(len(inputs) + 1),
+				Dna:    dna,
+			})
+		}
+		dna = []string{}
+	}
+	for _, line := range Lines(data) {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == "" {
+			flush()
+			continue
+		}
+		dna = append(dna, line)
+	}
+	flush()
+	return inputs
+}
+
+// Lines splits the data in trimmed lines. The buffer of the scanner holds the
+// whole data, so a line longer than the default 64KB is not cut short
+func Lines(data []byte) []string {
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(data)+1)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+	return lines
+}
+
+func Write(out io.Writer, results []Result, output string) error {
+	switch output {
+	case OUTPUT_JSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	case OUTPUT_JSONL:
+		encoder := json.NewEncoder(out)
+		for _, result := range results {
+			err := encoder.Encode(result)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return WriteTable(out, results)
+}
+
+func WriteTable(out io.Writer, results []Result) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tNAME\tSIZE\tVERDICT\tERROR")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", result.Source, result.Name, len(result.Dna), result.Verdict, result.Error)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,118 @@
+package analyze
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const MUTANT_JSON = `{"dna":["ATGCGA","CAGTGC","TTATGT","AGAAGG","CCCCTA","TCACTG"]}`
+const HUMAN_TEXT = "ATGCGA\nCAGTGC\nTTATTT\nAGACGG\nGCGTCA\nTCACTG\n"
+
+func run(args []string, stdin string) (int, string) {
+	out := &bytes.Buffer{}
+	code := Run(args, strings.NewReader(stdin), out, ioutil.Discard)
+	return code, out.String()
+}
+
+func TestRunHumanFromStdin(t *testing.T) {
+	code, out := run([]string{}, HUMAN_TEXT)
+	if code != EXIT_HUMAN {
+		t.Error("Expected exit code 0 for a human. Got:", code, out)
+	}
+	if !strings.Contains(out, "VERDICT") || !strings.Contains(out, "Human") {
+		t.Error("Expected a table with the verdict. Got:", out)
+	}
+}
+
+func TestRunMutantJSONL(t *testing.T) {
+	code, out := run([]string{"-output", "jsonl"}, MUTANT_JSON)
+	if code != EXIT_MUTANT {
+		t.Error("Expected exit code 1 for a mutant. Got:", code)
+	}
+	result := Result{}
+	err := json.Unmarshal([]byte(strings.TrimSpace(out)), &result)
+	if err != nil || result.Verdict != "Mutant" || result.Source != STDIN {
+		t.Error("Expected a mutant result per line. Got:", out, err)
+	}
+}
+
+func TestRunInvalidTakesPrecedence(t *testing.T) {
+	code, out := run([]string{"-output", "json"}, `[`+MUTANT_JSON+`,{"dna":["ATGX","CAGT","TTAT","AGAA"]}]`)
+	if code != EXIT_INVALID {
+		t.Error("Expected exit code 3 for invalid input. Got:", code)
+	}
+	results := []Result{}
+	json.Unmarshal([]byte(out), &results)
+	if len(results) != 2 || results[1].Verdict != INVALID || results[1].Error == "" {
+		t.Error("Expected the invalid dna with its error. Got:", out)
+	}
+}
+
+func TestRunUnknownOutput(t *testing.T) {
+	code, _ := run([]string{"-output", "xml"}, HUMAN_TEXT)
+	if code != EXIT_USAGE {
+		t.Error("Expected exit code 2 for an unknown output. Got:", code)
+	}
+}
+
+func TestRunMissingFile(t *testing.T) {
+	code, out := run([]string{"missing.json"}, "")
+	if code != EXIT_INVALID || !strings.Contains(out, "missing.json") {
+		t.Error("Expected the missing file to be invalid. Got:", code, out)
+	}
+}
+
+func TestRunFASTAFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "analyze")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dnas.fa")
+	ioutil.WriteFile(path, []byte(">mutant\nATGCGA\nCAGTGC\nTTATGT\nAGAAGG\nCCCCTA\nTCACTG\n>human\n"+HUMAN_TEXT), 0644)
+	code, out := run([]string{"-output", "json", path}, "")
+	results := []Result{}
+	json.Unmarshal([]byte(out), &results)
+	if code != EXIT_MUTANT || len(results) != 2 {
+		t.Error("Expected a result per record. Got:", code, out)
+		return
+	}
+	if results[0].Name != "mutant" || results[0].Verdict != "Mutant" || results[1].Name != "human" || results[1].Verdict != "Human" {
+		t.Error("Expected the records by their header. Got:", results)
+	}
+}
+
+func TestParseText(t *testing.T) {
+	inputs := ParseText("-", []byte("# comment\nAAAA\nCCCC\n\n\nGGGG\n"))
+	if len(inputs) != 2 || len(inputs[0].Dna) != 2 || inputs[1].Name != "2" {
+		t.Error("Expected the dnas split by blank lines. Got:", inputs)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	if DetectFormat("dnas.fasta", nil) != FORMAT_FASTA {
+		t.Error("Expected the format of the extension")
+	}
+	if DetectFormat("-", []byte("  [\"AAAA\"]")) != FORMAT_JSON {
+		t.Error("Expected json for content starting with [")
+	}
+	if DetectFormat("-", []byte(">name\nAAAA")) != FORMAT_FASTA {
+		t.Error("Expected fasta for content starting with >")
+	}
+	if DetectFormat("-", []byte("AAAA")) != FORMAT_TEXT {
+		t.Error("Expected text by default")
+	}
+}
+
+func TestLinesLongerThanTheScannerDefault(t *testing.T) {
+	long := strings.Repeat("A", 100000)
+	lines := Lines([]byte("ATGC\n" + long + "\nCAGT\n"))
+	if len(lines) != 3 || lines[1] != long || lines[2] != "CAGT" {
+		t.Error("Expected every line in full. Got:", len(lines))
+	}
+}
@@ -0,0 +1,246 @@
+// Package client calls the Magneto API. It turns the 200 and 403 of the
+// detector into a verdict, and the other responses into typed errors
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fpinatares/magneto/detector"
+)
+
+const (
+	DEFAULT_MAX_RETRIES = 3
+	DEFAULT_BACKOFF     = 100 * time.Millisecond
+	MAX_BACKOFF         = 5 * time.Second
+	DEFAULT_TIMEOUT     = 10 * time.Second
+	// BATCH_CONCURRENCY is the number of dnas of a batch analyzed at a time
+	BATCH_CONCURRENCY = 4
+)
+
+// DNA_UUID_HEADER is the header the detector answers the uuid of the dna in
+const DNA_UUID_HEADER = "X-Dna-Uuid"
+
+// Analysis is an analyzed dna. Its Type is detector.EnumDnaType.Mutant or
+// detector.EnumDnaType.Human
+type Analysis = detector.DnaData
+
+// Stats has the count of every type, by its lowercase name
+type Stats struct {
+	Counts   map[string]int
+	Ratio    float64
+	Warnings []string
+}
+
+// Client retries the 5xx and 429 responses and the failed connections, waiting
+// Backoff and then twice as long each time
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{
+			Timeout: DEFAULT_TIMEOUT,
+		},
+		MaxRetries: DEFAULT_MAX_RETRIES,
+		Backoff:    DEFAULT_BACKOFF,
+	}
+}
+
+// Analyze sends the dna to the detector. A human is not an error. A retried
+// request may count the dna twice in the stats, when the first attempt
+// reached the detector
+func (c *Client) Analyze(ctx context.Context, dna []string) (Analysis, error) {
+	analysis := Analysis{
+		Dna: dna,
+	}
+	body, err := json.Marshal(map[string][]string{"dna": dna})
+	if err != nil {
+		return analysis, err
+	}
+	response, err := c.Do(ctx, http.MethodPost, "/mutant", body)
+	if err != nil {
+		return analysis, err
+	}
+	analysis.Uuid = response.Header.Get(DNA_UUID_HEADER)
+	switch response.StatusCode {
+	case http.StatusOK:
+		analysis.Type = detector.EnumDnaType.Mutant
+	case http.StatusForbidden:
+		analysis.Type = detector.EnumDnaType.Human
+	default:
+		return analysis, NewAPIError(response)
+	}
+	return analysis, nil
+}
+
+// AnalyzeBatch analyzes every dna, BATCH_CONCURRENCY at a time. The analyses
+// keep the order of the dnas, and a BatchError has the ones that failed
+func (c *Client) AnalyzeBatch(ctx context.Context, dnas [][]string) ([]Analysis, error) {
+	analyses := make([]Analysis, len(dnas))
+	batchErr := &BatchError{
+		Errors: map[int]error{},
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, BATCH_CONCURRENCY)
+	for i, dna := range dnas {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, dna []string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			analysis, err := c.Analyze(ctx, dna)
+			analyses[i] = analysis
+			if err != nil {
+				mu.Lock()
+				batchErr.Errors[i] = err
+				mu.Unlock()
+			}
+		}(i, dna)
+	}
+	wg.Wait()
+	if len(batchErr.Errors) > 0 {
+		return analyses, batchErr
+	}
+	return analyses, nil
+}
+
+// GetAnalysis looks up a dna by the uuid Analyze returned. It is ErrNotFound
+// until the dna is saved, which happens shortly after the analysis
+func (c *Client) GetAnalysis(ctx context.Context, uuid string) (Analysis, error) {
+	analysis := Analysis{}
+	response, err := c.Do(ctx, http.MethodGet, "/dnas/"+url.PathEscape(uuid), nil)
+	if err != nil {
+		return analysis, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return analysis, NewAPIError(response)
+	}
+	err = json.Unmarshal(response.Body, &analysis)
+	return analysis, err
+}
+
+func (c *Client) GetStats(ctx context.Context) (Stats, error) {
+	stats := Stats{
+		Counts: map[string]int{},
+	}
+	response, err := c.Do(ctx, http.MethodGet, "/stats", nil)
+	if err != nil {
+		return stats, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return stats, NewAPIError(response)
+	}
+	fields := map[string]json.RawMessage{}
+	err = json.Unmarshal(response.Body, &fields)
+	if err != nil {
+		return stats, err
+	}
+	for name, value := range fields {
+		switch {
+		case name == "ratio":
+			err = json.Unmarshal(value, &stats.Ratio)
+		case name == "warnings":
+			err = json.Unmarshal(value, &stats.Warnings)
+		case strings.HasPrefix(name, "count_") && strings.HasSuffix(name, "_dna"):
+			var count int
+			err = json.Unmarshal(value, &count)
+			stats.Counts[strings.TrimSuffix(strings.TrimPrefix(name, "count_"), "_dna")] = count
+		}
+		if err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func NewAPIError(response Response) error {
+	return &APIError{
+		StatusCode: response.StatusCode,
+		Body:       strings.TrimSpace(string(response.Body)),
+	}
+}
+
+// Do sends the request, retrying it until it gets a response that is not
+// retryable, the retries run out or the context is done
+func (c *Client) Do(ctx context.Context, method string, path string, body []byte) (Response, error) {
+	var response Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		response, err = c.send(ctx, method, path, body)
+		if ctx.Err() != nil {
+			return response, ctx.Err()
+		}
+		if err == nil && !IsRetryableStatus(response.StatusCode) {
+			return response, nil
+		}
+		if attempt >= c.MaxRetries {
+			if err != nil {
+				return response, err
+			}
+			return response, NewAPIError(response)
+		}
+		timer := time.NewTimer(c.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return response, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, path string, body []byte) (Response, error) {
+	response := Response{}
+	req, err := http.NewRequest(method, c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return response, err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return response, err
+	}
+	defer res.Body.Close()
+	response.StatusCode = res.StatusCode
+	response.Header = res.Header
+	response.Body, err = ioutil.ReadAll(res.Body)
+	return response, err
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	backoff := c.Backoff
+	for i := 0; i < attempt && backoff < MAX_BACKOFF; i++ {
+		backoff *= 2
+	}
+	if backoff > MAX_BACKOFF {
+		return MAX_BACKOFF
+	}
+	return backoff
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/fpinatares/magneto/local"
+	"github.com/fpinatares/magneto/mutant"
+	"github.com/fpinatares/magneto/server"
+)
+
+var MUTANT = []string{"ATGCGA", "CAGTGC", "TTATGT", "AGAAGG", "CCCCTA", "TCACTG"}
+var HUMAN = []string{"ATGCGA", "CAGTGC", "TTATTT", "AGACGG", "GCGTCA", "TCACTG"}
+
+type mockSNSClient struct {
+	snsiface.SNSAPI
+}
+
+func (m *mockSNSClient) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	return &sns.PublishOutput{}, nil
+}
+
+func newClient(url string) *Client {
+	c := New(url)
+	c.Backoff = time.Millisecond
+	return c
+}
+
+func TestAnalyze(t *testing.T) {
+	srv := httptest.NewServer(local.NewDevRoutes())
+	defer srv.Close()
+	c := newClient(srv.URL)
+	analysis, err := c.Analyze(context.Background(), MUTANT)
+	if err != nil || analysis.Type != "Mutant" || analysis.Uuid == "" {
+		t.Error("Expected a mutant with its uuid. Got:", analysis, err)
+	}
+	analysis, err = c.Analyze(context.Background(), HUMAN)
+	if err != nil || analysis.Type != "Human" {
+		t.Error("Expected a human, which is not an error. Got:", analysis, err)
+	}
+	found, err := c.GetAnalysis(context.Background(), analysis.Uuid)
+	if err != nil || found.Uuid != analysis.Uuid || found.Type != "Human" || len(found.Dna) != 6 {
+		t.Error("Expected the analysis by its uuid. Got:", found, err)
+	}
+	stats, err := c.GetStats(context.Background())
+	if err != nil || stats.Counts["mutant"] != 1 || stats.Counts["human"] != 1 || stats.Ratio != 1 {
+		t.Error("Expected the stats of both dnas. Got:", stats, err)
+	}
+}
+
+func TestAnalyzeInvalidDna(t *testing.T) {
+	srv := httptest.NewServer(local.NewDevRoutes())
+	defer srv.Close()
+	_, err := newClient(srv.URL).Analyze(context.Background(), []string{"XXXX"})
+	if !errors.Is(err, ErrInvalidDna) {
+		t.Error("Expected an invalid dna error. Got:", err)
+	}
+}
+
+func TestGetAnalysisNotFound(t *testing.T) {
+	srv := httptest.NewServer(local.NewDevRoutes())
+	defer srv.Close()
+	_, err := newClient(srv.URL).GetAnalysis(context.Background(), "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("Expected a not found error. Got:", err)
+	}
+}
+
+func TestAnalyzeBatch(t *testing.T) {
+	srv := httptest.NewServer(local.NewDevRoutes())
+	defer srv.Close()
+	analyses, err := newClient(srv.URL).AnalyzeBatch(context.Background(), [][]string{MUTANT, {"XXXX"}, HUMAN})
+	batchErr, ok := err.(*BatchError)
+	if !ok || len(batchErr.Errors) != 1 || !errors.Is(batchErr.Errors[1], ErrInvalidDna) {
+		t.Error("Expected the error of the invalid dna. Got:", err)
+	}
+	if len(analyses) != 3 || analyses[0].Type != "Mutant" || analyses[2].Type != "Human" {
+		t.Error("Expected the analyses in order. Got:", analyses)
+	}
+}
+
+func TestAnalyzeNotAcceptable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+	}))
+	defer srv.Close()
+	_, err := newClient(srv.URL).Analyze(context.Background(), MUTANT)
+	var apiErr *APIError
+	if !errors.Is(err, ErrNotAcceptable) || !errors.As(err, &apiErr) || apiErr.StatusCode != 406 {
+		t.Error("Expected a not acceptable error. Got:", err)
+	}
+}
+
+func TestAnalyzeRetriesServerErrors(t *testing.T) {
+	var calls int32
+	handler := server.Adapt(mutant.NewHandler(&mockSNSClient{}).DetectMutant)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	analysis, err := newClient(srv.URL).Analyze(context.Background(), MUTANT)
+	if err != nil || analysis.Type != "Mutant" || calls != 3 {
+		t.Error("Expected the third attempt to succeed. Got:", analysis, err, calls)
+	}
+}
+
+func TestAnalyzeRetriesRunOut(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	_, err := newClient(srv.URL).Analyze(context.Background(), MUTANT)
+	if !errors.Is(err, ErrServer) || calls != DEFAULT_MAX_RETRIES+1 {
+		t.Error("Expected a server error after every retry. Got:", err, calls)
+	}
+}
+
+func TestAnalyzeContextCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	c := New(srv.URL)
+	c.Backoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.Analyze(ctx, MUTANT)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected the context error instead of waiting to retry. Got:", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := New("http://localhost")
+	if c.backoff(0) != DEFAULT_BACKOFF || c.backoff(2) != 4*DEFAULT_BACKOFF || c.backoff(20) != MAX_BACKOFF {
+		t.Error("Expected the backoff to double up to the maximum. Got:", c.backoff(0), c.backoff(2), c.backoff(20))
+	}
+}
+
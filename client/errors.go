@@ -0,0 +1,59 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrInvalidDna is answered with 400 to a dna that is not a valid matrix
+	ErrInvalidDna = errors.New("invalid dna")
+	// ErrNotAcceptable is answered with 406 to a body that is not json
+	ErrNotAcceptable = errors.New("not acceptable")
+	ErrNotFound      = errors.New("not found")
+	// ErrServer is any 5xx, which is retried
+	ErrServer = errors.New("server error")
+)
+
+// APIError is an unexpected response. It wraps the error of its status code,
+// so it can be checked with errors.Is
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("magneto: %d %s", e.StatusCode, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrInvalidDna
+	case e.StatusCode == http.StatusNotAcceptable:
+		return ErrNotAcceptable
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServer
+	}
+	return nil
+}
+
+func (e *APIError) Retryable() bool {
+	return IsRetryableStatus(e.StatusCode)
+}
+
+func IsRetryableStatus(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
+
+// BatchError has the error of every dna of the batch that failed, by its index
+type BatchError struct {
+	Errors map[int]error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("magneto: %d dnas of the batch failed", len(e.Errors))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/fpinatares/magneto/adapter"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/middleware"
+	"github.com/fpinatares/magneto/mutant"
+	"github.com/fpinatares/magneto/repository"
+	"github.com/fpinatares/magneto/router"
+	"github.com/fpinatares/magneto/stats"
+	"github.com/fpinatares/magneto/tracing"
+)
+
+// The api lambda serves every resource of the API, for deployments that run
+// one function instead of the mutant and stat ones
+func main() {
+	logging.Setup()
+	tracing.Setup()
+	stats.RegisterDnaTypesFromEnv()
+	svc := tracing.NewDynamoDB(stats.GetDynamoDBClient())
+	config := repository.ConfigFromEnv()
+	config.DnaTypes = stats.RegisteredTypes()
+	repo, err := repository.New(config, svc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ttl, cacheControl := stats.CacheConfigFromEnv()
+	stat := stats.NewHandler(svc, repo, stats.NewStatCache(ttl), cacheControl)
+	detect := mutant.NewHandler(mutant.GetSNSClient())
+	r := router.New(router.Routes(detect.DetectMutant, stat.GetStats))
+	lambda.Start(adapter.Handler(middleware.Default(r.Handle)))
+}
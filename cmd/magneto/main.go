@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fpinatares/magneto/analyze"
+	"github.com/fpinatares/magneto/local"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/tracing"
+)
+
+const USAGE = `usage:
+  magneto dev [-addr address]
+  magneto analyze [-format auto|json|fasta|text] [-output table|json|jsonl] [file ...]`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, USAGE)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "dev":
+		logging.Setup()
+		tracing.Setup()
+		err := local.RunDev(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "analyze":
+		os.Exit(analyze.Run(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	default:
+		fmt.Fprintln(os.Stderr, USAGE)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/fpinatares/magneto/adapter"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/middleware"
+	"github.com/fpinatares/magneto/mutant"
+	"github.com/fpinatares/magneto/tracing"
+)
+
+func main() {
+	logging.Setup()
+	tracing.Setup()
+	d := mutant.NewHandler(mutant.GetSNSClient())
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		err := d.RunServe(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	lambda.Start(adapter.Handler(middleware.Default(d.DetectMutant)))
+}
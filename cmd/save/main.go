@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/repository"
+	"github.com/fpinatares/magneto/stats"
+	"github.com/fpinatares/magneto/storage"
+	"github.com/fpinatares/magneto/tracing"
+)
+
+func main() {
+	logging.Setup()
+	tracing.Setup()
+	stats.RegisterDnaTypesFromEnv()
+	svc := tracing.NewDynamoDB(storage.GetDynamoDBClient())
+	config := repository.ConfigFromEnv()
+	config.DnaTypes = stats.RegisteredTypes()
+	repo, err := repository.New(config, svc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if dynamo, ok := repo.(*repository.DynamoDBRepository); ok {
+		err = dynamo.RecordShards()
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = dynamo.RecordTypes()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	d := storage.NewHandler(svc, repo)
+	d.DnaTypes = config.DnaTypes
+	d.StatsFromStream = os.Getenv("STATS_SOURCE") == storage.STREAM_STATS_SOURCE
+
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		err := d.RunReplay(os.Args[2:], os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		err := d.RunServe(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if os.Getenv("EVENT_SOURCE") == storage.SQS_EVENT_SOURCE {
+		lambda.Start(func(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+			defer tracing.Flush(ctx)
+			return d.WithLogger(logging.StartInvocation(ctx)).SaveBatch(event)
+		})
+		return
+	}
+	lambda.Start(func(ctx context.Context, event events.SNSEvent) error {
+		defer tracing.Flush(ctx)
+		return d.WithLogger(logging.StartInvocation(ctx)).Save(event)
+	})
+}
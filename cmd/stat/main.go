@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/fpinatares/magneto/adapter"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/middleware"
+	"github.com/fpinatares/magneto/repository"
+	"github.com/fpinatares/magneto/stats"
+	"github.com/fpinatares/magneto/tracing"
+)
+
+func main() {
+	logging.Setup()
+	tracing.Setup()
+	stats.RegisterDnaTypesFromEnv()
+	svc := tracing.NewDynamoDB(stats.GetDynamoDBClient())
+	config := repository.ConfigFromEnv()
+	config.DnaTypes = stats.RegisteredTypes()
+	repo, err := repository.New(config, svc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ttl, cacheControl := stats.CacheConfigFromEnv()
+	d := stats.NewHandler(svc, repo, stats.NewStatCache(ttl), cacheControl)
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		err := d.RunServe(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	lambda.Start(adapter.Handler(middleware.Default(d.GetStats)))
+}
@@ -0,0 +1,114 @@
+package detector
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NECESSARY_SECUENCE is the number of equal bases that make a sequence, and
+// NECESSARY_SECUENCES the number of sequences that make a mutant
+const NECESSARY_SECUENCE = 4
+const NECESSARY_SECUENCES = 2
+
+var validBases = regexp.MustCompile(`^[ACGT]+$`)
+
+// GetDnaType is Mutant or Human. The dna must be valid
+func GetDnaType(dna []string) string {
+	if IsMutant(dna) {
+		return EnumDnaType.Mutant
+	} else {
+		return EnumDnaType.Human
+	}
+}
+
+// ValidateDna checks that every row only has the bases A, C, G and T
+func ValidateDna(dna []string) error {
+	for _, s := range dna {
+		if !validBases.MatchString(s) {
+			return errors.New("the dna provided does not match a possible dna")
+		}
+	}
+	return nil
+}
+
+// ValidateMatrix checks that the dna is a square matrix, which IsMutant expects
+func ValidateMatrix(dna []string) error {
+	if len(dna) == 0 {
+		return errors.New("the dna is empty")
+	}
+	for i, row := range dna {
+		if len(row) != len(dna) {
+			return fmt.Errorf("the row %d has %d bases, the dna must be a square matrix of %d", i+1, len(row), len(dna))
+		}
+	}
+	return nil
+}
+
+// IsMutant looks for sequences until it finds the necessary ones
+func IsMutant(dna []string) bool {
+	length := len(dna)
+	sequences := 0
+	for i := 0; i < length; i++ {
+		for j := 0; j < length; j++ {
+			isSequence := IsSequence(i, j, dna)
+			if isSequence {
+				sequences++
+				if sequences >= NECESSARY_SECUENCES {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// IsSequence is true when a sequence starts at the position, going right,
+// down or down and right
+func IsSequence(i int, j int, dna []string) bool {
+	return IsHorizontalSequence(i, j, dna) ||
+		IsVerticalSequence(i, j, dna) ||
+		IsDiagonalSequence(i, j, dna)
+}
+
+func IsHorizontalSequence(indexI int, indexJ int, dna []string) bool {
+	if indexJ > len(dna)-NECESSARY_SECUENCE {
+		return false
+	}
+	c := strings.Split(dna[indexI], "")[indexJ]
+	for i := 1; i < NECESSARY_SECUENCE; i++ {
+		if c != strings.Split(dna[indexI], "")[indexJ+i] {
+			return false
+		}
+	}
+	return true
+}
+
+func IsVerticalSequence(indexI int, indexJ int, dna []string) bool {
+	if indexI > len(dna)-NECESSARY_SECUENCE {
+		return false
+	}
+	c := strings.Split(dna[indexI], "")[indexJ]
+	for i := 1; i < NECESSARY_SECUENCE; i++ {
+		if c != strings.Split(dna[indexI+i], "")[indexJ] {
+			return false
+		}
+	}
+	return true
+}
+
+func IsDiagonalSequence(indexI int, indexJ int, dna []string) bool {
+	checkHorizontal := indexJ <= len(dna)-NECESSARY_SECUENCE
+	checkVertical := indexI <= len(dna)-NECESSARY_SECUENCE
+	if !checkHorizontal || !checkVertical {
+		return false
+	}
+	c := strings.Split(dna[indexI], "")[indexJ]
+	for i := 1; i < NECESSARY_SECUENCE; i++ {
+		if c != strings.Split(dna[indexI+i], "")[indexJ+i] {
+			return false
+		}
+	}
+	return true
+}
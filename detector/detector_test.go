@@ -1,104 +1,9 @@
-package main
+package detector
 
 import (
 	"testing"
-
-	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/service/sns"
-	"github.com/aws/aws-sdk-go/service/sns/snsiface"
 )
 
-type mockSNSClient struct {
-	snsiface.SNSAPI
-}
-
-func (m *mockSNSClient) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
-	return nil, nil
-}
-
-func TestDetectMutantWithNotAcceptableContentType(t *testing.T) {
-	req := events.APIGatewayProxyRequest{
-		Headers: map[string]string{},
-	}
-	req.Headers["content-type"] = "application/xml"
-	d := dependencies{
-		notifier: &mockSNSClient{},
-	}
-	response, _ := d.DetectMutant(req)
-	if response.StatusCode != 406 {
-		t.Error("406 - Not Acceptable http status code expected. Got:", response.StatusCode)
-	}
-}
-
-func TestDetectMutantWithEmptyBody(t *testing.T) {
-	req := events.APIGatewayProxyRequest{
-		Headers: map[string]string{},
-		Body:    "",
-	}
-	req.Headers["content-type"] = "application/json"
-	d := dependencies{
-		notifier: &mockSNSClient{},
-	}
-	response, _ := d.DetectMutant(req)
-	if response.StatusCode != 400 {
-		t.Error("400 - Bad Request http status code expected. Got:", response.StatusCode)
-	}
-}
-
-func TestDetectNoMutantRespondsForbidden(t *testing.T) {
-	req := events.APIGatewayProxyRequest{
-		Headers: map[string]string{},
-		Body:    "{\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"]}",
-	}
-	req.Headers["content-type"] = "application/json"
-	d := dependencies{
-		notifier: &mockSNSClient{},
-	}
-	response, _ := d.DetectMutant(req)
-	if response.StatusCode != 403 {
-		t.Error("403 - Forbidden http status code expected. Got:", response.StatusCode)
-	}
-}
-
-func TestDetectMutantRespondsOk(t *testing.T) {
-	req := events.APIGatewayProxyRequest{
-		Headers: map[string]string{},
-		Body:    "{\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATGT\",\"AGAAGG\",\"CCCCTA\",\"TCACTG\"]}",
-	}
-	req.Headers["content-type"] = "application/json"
-	d := dependencies{
-		notifier: &mockSNSClient{},
-	}
-	response, _ := d.DetectMutant(req)
-	if response.StatusCode != 200 {
-		t.Error("200 - Ok http status code expected. Got:", response.StatusCode)
-	}
-}
-
-func TestErrorParsingEmptyRequest(t *testing.T) {
-	body := ""
-	_, err := ParseRequest(body)
-	if err == nil {
-		t.Error("Expected error while parsing an empty request", err)
-	}
-}
-
-func TestParseRequest(t *testing.T) {
-	body := "{\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"]}"
-	_, err := ParseRequest(body)
-	if err != nil {
-		t.Error("No error expected while parsing request", err)
-	}
-}
-
-func TestInvalidDnaParsingRequest(t *testing.T) {
-	body := "{\"dna\":[\"XTGAGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"]}"
-	_, err := ParseRequest(body)
-	if err == nil {
-		t.Error("Expected error while parsing request with an invalid dna", err)
-	}
-}
-
 func TestGetMutantDnaType(t *testing.T) {
 	dna := []string{"ATGCGA", "CAGTGC", "TTATGT", "AGAAGG", "CCCCTA", "TCACTG"}
 	dnaType := GetDnaType(dna)
@@ -187,13 +92,6 @@ func TestValidDna(t *testing.T) {
 	}
 }
 
-func TestRespondOK(t *testing.T) {
-	response, err := Respond(200)
-	if response.Body != "OK" || err != nil {
-		t.Error("OK response expected")
-	}
-}
-
 func TestNotMutant(t *testing.T) {
 	dna := []string{"ATGCGA", "CAGTGC", "TTATTT", "AGACGG", "GCGTCA", "TCACTG"}
 	if IsMutant(dna) {
@@ -207,3 +105,15 @@ func TestIsMutant(t *testing.T) {
 		t.Error("Expected a mutant DNA")
 	}
 }
+
+func TestValidateMatrix(t *testing.T) {
+	if ValidateMatrix([]string{"AAAA", "CCC"}) == nil {
+		t.Error("Expected error for a dna that is not square")
+	}
+	if ValidateMatrix([]string{}) == nil {
+		t.Error("Expected error for an empty dna")
+	}
+	if ValidateMatrix([]string{"AC", "GT"}) != nil {
+		t.Error("No error expected for a square dna")
+	}
+}
@@ -0,0 +1,32 @@
+// Package detector tells mutant dnas from human ones. It has no dependencies
+// beyond the standard library, so the verdict can be embedded in any service.
+//
+// A dna is a square matrix of the bases A, C, G and T, given as its rows.
+// Check it with ValidateMatrix and ValidateDna, then get its type with
+// GetDnaType, which is Mutant when it has more than one sequence of four
+// equal bases, horizontal, vertical or diagonal
+package detector
+
+var EnumDnaType = DnaTypes()
+
+func DnaTypes() *DnaType {
+	return &DnaType{
+		Human:  "Human",
+		Mutant: "Mutant",
+	}
+}
+
+// DnaType has the names of the types, which are the ones stored and reported
+// by the stats
+type DnaType struct {
+	Human  string
+	Mutant string
+}
+
+// DnaData is an analyzed dna, as the detector publishes it and the repository
+// stores it
+type DnaData struct {
+	Uuid string   `json:"uuid"`
+	Dna  []string `json:"dna"`
+	Type string   `json:"type"`
+}
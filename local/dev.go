@@ -0,0 +1,45 @@
+package local
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/fpinatares/magneto/mutant"
+	"github.com/fpinatares/magneto/repository"
+	"github.com/fpinatares/magneto/middleware"
+	"github.com/fpinatares/magneto/router"
+	"github.com/fpinatares/magneto/server"
+	"github.com/fpinatares/magneto/stats"
+	"github.com/fpinatares/magneto/storage"
+	"github.com/fpinatares/magneto/tracing"
+)
+
+const DEFAULT_DEV_ADDRESS = ":8080"
+
+// NewDevRoutes wires the detector, the save consumer and the stats reader to
+// an in-memory bus and store. The stats are not cached, so a saved dna shows
+// up in them right away
+func NewDevRoutes() http.Handler {
+	db := tracing.NewDynamoDB(NewDynamoDB(Tables))
+	bus := NewBus()
+	repo := repository.NewDynamoDBRepository(db)
+	repo.DnaTypes = stats.RegisteredTypes()
+	save := storage.NewHandler(db, repo)
+	save.DnaTypes = repo.DnaTypes
+	bus.AddSubscriber(save.Save)
+	detector := mutant.NewHandler(bus)
+	stat := stats.NewHandler(db, repo, nil, "")
+	routes := router.Routes(detector.DetectMutant, stat.GetStats)
+	routes = append(routes, server.Route{Method: http.MethodPost, Path: "/save", Handler: save.SaveDirect})
+	return server.Adapt(middleware.Default(router.New(routes).Handle))
+}
+
+func RunDev(args []string) error {
+	flags := flag.NewFlagSet("dev", flag.ContinueOnError)
+	address := server.AddressFlag(flags, DEFAULT_DEV_ADDRESS)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	return server.Serve(*address, NewDevRoutes())
+}
@@ -0,0 +1,30 @@
+package local
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDevSavesDetectedDna(t *testing.T) {
+	routes := NewDevRoutes()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/mutant", strings.NewReader(`{"dna":["ATGCGA","CAGTGC","TTATGT","AGAAGG","CCCCTA","TCACTG"]}`))
+	r.Header.Set("Content-Type", "application/json")
+	routes.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Error("Expected the dna to be a mutant. Got:", w.Code)
+	}
+	w = httptest.NewRecorder()
+	routes.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	stat := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &stat)
+	if err != nil {
+		t.Error("No error expected reading the stats", err, w.Body.String())
+	}
+	if stat["count_mutant_dna"] != 1.0 || stat["count_human_dna"] != 0.0 {
+		t.Error("Expected the mutant to be counted. Got:", w.Body.String())
+	}
+}
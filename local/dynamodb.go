@@ -0,0 +1,366 @@
+// Package local has in-memory versions of the AWS services the lambdas use,
+// so the whole pipeline can run in a single process without AWS
+package local
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Tables are the partition keys of the tables the lambdas use
+var Tables = map[string]string{
+	"dnas":                 "uuid",
+	"stats":                "dna_type",
+	"stats_buckets":        "bucket",
+	"stats_dimensions":     "dimension",
+	"processed_messages":   "message_id",
+	"quarantined_messages": "message_id",
+	"processed_events":     "event_id",
+}
+
+var (
+	addExpression       = regexp.MustCompile(`^ADD\s+(#?\w+)\s+(:\w+)$`)
+	notExistsExpression = regexp.MustCompile(`^attribute_not_exists\((#?\w+)\)$`)
+)
+
+type Item = map[string]*dynamodb.AttributeValue
+
+// DynamoDB keeps the tables in memory. It supports the subset of the API the
+// lambdas use: keys made of a partition key, updates with ADD and conditions
+// with attribute_not_exists. The methods not implemented panic
+type DynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	mu     sync.Mutex
+	keys   map[string]string
+	tables map[string]map[string]Item
+}
+
+func NewDynamoDB(keys map[string]string) *DynamoDB {
+	db := &DynamoDB{
+		keys:   map[string]string{},
+		tables: map[string]map[string]Item{},
+	}
+	for table, key := range keys {
+		db.keys[table] = key
+		db.tables[table] = map[string]Item{}
+	}
+	return db
+}
+
+func (db *DynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	put := &dynamodb.Put{
+		TableName:                input.TableName,
+		Item:                     input.Item,
+		ConditionExpression:      input.ConditionExpression,
+		ExpressionAttributeNames: input.ExpressionAttributeNames,
+	}
+	err := db.check(aws.StringValue(put.TableName), put.Item, put.ConditionExpression, put.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.PutItemOutput{}, db.put(put)
+}
+
+func (db *DynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	update := &dynamodb.Update{
+		TableName:                 input.TableName,
+		Key:                       input.Key,
+		ConditionExpression:       input.ConditionExpression,
+		ExpressionAttributeNames:  input.ExpressionAttributeNames,
+		ExpressionAttributeValues: input.ExpressionAttributeValues,
+		UpdateExpression:          input.UpdateExpression,
+	}
+	err := db.check(aws.StringValue(update.TableName), update.Key, update.ConditionExpression, update.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.UpdateItemOutput{}, db.update(update)
+}
+
+func (db *DynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	item, err := db.get(aws.StringValue(input.TableName), input.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (db *DynamoDB) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	table, id, err := db.locate(aws.StringValue(input.TableName), input.Key)
+	if err != nil {
+		return nil, err
+	}
+	delete(table, id)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// Scan returns the items sorted by key. A segment has the items whose key
+// hashes to it
+func (db *DynamoDB) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	table, ok := db.tables[aws.StringValue(input.TableName)]
+	if !ok {
+		return nil, NotFound(aws.StringValue(input.TableName))
+	}
+	ids := []string{}
+	for id := range table {
+		if input.TotalSegments == nil || Segment(id, *input.TotalSegments) == aws.Int64Value(input.Segment) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	output := &dynamodb.ScanOutput{
+		Items: []Item{},
+	}
+	for _, id := range ids {
+		output.Items = append(output.Items, Copy(table[id]))
+	}
+	output.Count = aws.Int64(int64(len(output.Items)))
+	output.ScannedCount = output.Count
+	return output, nil
+}
+
+func (db *DynamoDB) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	output, err := db.Scan(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+func (db *DynamoDB) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	output := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]Item{},
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{},
+	}
+	for name, request := range input.RequestItems {
+		output.Responses[name] = []Item{}
+		for _, key := range request.Keys {
+			item, err := db.get(name, key)
+			if err != nil {
+				return nil, err
+			}
+			if item != nil {
+				output.Responses[name] = append(output.Responses[name], item)
+			}
+		}
+	}
+	return output, nil
+}
+
+// TransactWriteItems checks every condition before writing anything, and
+// cancels the transaction with the reason of each item when one fails
+func (db *DynamoDB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	reasons := []*dynamodb.CancellationReason{}
+	failed := false
+	for _, item := range input.TransactItems {
+		var err error
+		switch {
+		case item.Put != nil:
+			err = db.check(aws.StringValue(item.Put.TableName), item.Put.Item, item.Put.ConditionExpression, item.Put.ExpressionAttributeNames)
+		case item.Update != nil:
+			err = db.check(aws.StringValue(item.Update.TableName), item.Update.Key, item.Update.ConditionExpression, item.Update.ExpressionAttributeNames)
+		case item.Delete != nil:
+			err = db.check(aws.StringValue(item.Delete.TableName), item.Delete.Key, item.Delete.ConditionExpression, item.Delete.ExpressionAttributeNames)
+		default:
+			return nil, awserr.New("ValidationException", "unsupported transact item", nil)
+		}
+		reason := &dynamodb.CancellationReason{Code: aws.String("None")}
+		if err != nil {
+			failed = true
+			code := "ValidationError"
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+				code = "ConditionalCheckFailed"
+			}
+			reason = &dynamodb.CancellationReason{Code: aws.String(code), Message: aws.String(err.Error())}
+		}
+		reasons = append(reasons, reason)
+	}
+	if failed {
+		return nil, &dynamodb.TransactionCanceledException{
+			Message_:            aws.String("Transaction cancelled"),
+			CancellationReasons: reasons,
+		}
+	}
+	for _, item := range input.TransactItems {
+		var err error
+		switch {
+		case item.Put != nil:
+			err = db.put(item.Put)
+		case item.Update != nil:
+			err = db.update(item.Update)
+		case item.Delete != nil:
+			var table map[string]Item
+			var id string
+			table, id, err = db.locate(aws.StringValue(item.Delete.TableName), item.Delete.Key)
+			if err == nil {
+				delete(table, id)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func (db *DynamoDB) put(put *dynamodb.Put) error {
+	table, id, err := db.locate(aws.StringValue(put.TableName), put.Item)
+	if err != nil {
+		return err
+	}
+	table[id] = Copy(put.Item)
+	return nil
+}
+
+func (db *DynamoDB) update(update *dynamodb.Update) error {
+	table, id, err := db.locate(aws.StringValue(update.TableName), update.Key)
+	if err != nil {
+		return err
+	}
+	match := addExpression.FindStringSubmatch(strings.TrimSpace(aws.StringValue(update.UpdateExpression)))
+	if match == nil {
+		return awserr.New("ValidationException", "only ADD update expressions are supported", nil)
+	}
+	name := Resolve(match[1], update.ExpressionAttributeNames)
+	value, ok := update.ExpressionAttributeValues[match[2]]
+	if !ok || value.N == nil {
+		return awserr.New("ValidationException", "ADD needs a number value for "+match[2], nil)
+	}
+	item, ok := table[id]
+	if !ok {
+		item = Copy(update.Key)
+	}
+	total, err := Add(item[name], aws.StringValue(value.N))
+	if err != nil {
+		return err
+	}
+	item[name] = &dynamodb.AttributeValue{N: aws.String(total)}
+	table[id] = item
+	return nil
+}
+
+func (db *DynamoDB) get(name string, key Item) (Item, error) {
+	table, id, err := db.locate(name, key)
+	if err != nil {
+		return nil, err
+	}
+	item, ok := table[id]
+	if !ok {
+		return nil, nil
+	}
+	return Copy(item), nil
+}
+
+// check evaluates the attribute_not_exists condition, the only one supported
+func (db *DynamoDB) check(name string, key Item, condition *string, names map[string]*string) error {
+	table, id, err := db.locate(name, key)
+	if err != nil || condition == nil {
+		return err
+	}
+	match := notExistsExpression.FindStringSubmatch(strings.TrimSpace(*condition))
+	if match == nil {
+		return awserr.New("ValidationException", "only attribute_not_exists conditions are supported", nil)
+	}
+	if item, ok := table[id]; ok && item[Resolve(match[1], names)] != nil {
+		return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	return nil
+}
+
+func (db *DynamoDB) locate(name string, key Item) (map[string]Item, string, error) {
+	table, ok := db.tables[name]
+	if !ok {
+		return nil, "", NotFound(name)
+	}
+	value, ok := key[db.keys[name]]
+	if !ok || value.S == nil && value.N == nil {
+		return nil, "", awserr.New("ValidationException", "missing key "+db.keys[name], nil)
+	}
+	if value.S != nil {
+		return table, "S" + *value.S, nil
+	}
+	return table, "N" + *value.N, nil
+}
+
+func NotFound(table string) error {
+	return awserr.New(dynamodb.ErrCodeResourceNotFoundException, "Requested resource not found: "+table, nil)
+}
+
+func Resolve(name string, names map[string]*string) string {
+	if alias, ok := names[name]; ok {
+		return aws.StringValue(alias)
+	}
+	return name
+}
+
+func Add(current *dynamodb.AttributeValue, value string) (string, error) {
+	delta, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	if current == nil || current.N == nil {
+		return strconv.FormatInt(delta, 10), nil
+	}
+	total, err := strconv.ParseInt(*current.N, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(total+delta, 10), nil
+}
+
+func Segment(id string, segments int64) int64 {
+	hash := fnv.New32a()
+	fmt.Fprint(hash, id)
+	return int64(hash.Sum32()) % segments
+}
+
+// Copy clones the item, so callers can not change the stored one
+func Copy(item Item) Item {
+	copied := Item{}
+	for name, value := range item {
+		copied[name] = CopyValue(value)
+	}
+	return copied
+}
+
+func CopyValue(value *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	if value.L != nil {
+		copied.L = []*dynamodb.AttributeValue{}
+		for _, element := range value.L {
+			copied.L = append(copied.L, CopyValue(element))
+		}
+	}
+	if value.M != nil {
+		copied.M = Copy(value.M)
+	}
+	return &copied
+}
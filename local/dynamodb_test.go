@@ -0,0 +1,157 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func key(value string) Item {
+	return Item{"uuid": {S: aws.String(value)}}
+}
+
+func add(db *DynamoDB, id string, value string) error {
+	_, err := db.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String("stats"),
+		Key:                       Item{"dna_type": {S: aws.String(id)}},
+		ExpressionAttributeNames:  map[string]*string{"#type": aws.String("Mutant")},
+		ExpressionAttributeValues: Item{":inc": {N: aws.String(value)}},
+		UpdateExpression:          aws.String("ADD #type :inc"),
+	})
+	return err
+}
+
+func TestUpdateItemAdd(t *testing.T) {
+	db := NewDynamoDB(Tables)
+	add(db, "Mutant", "1")
+	add(db, "Mutant", "2")
+	result, err := db.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("stats"),
+		Key:       Item{"dna_type": {S: aws.String("Mutant")}},
+	})
+	if err != nil {
+		t.Error("No error expected reading the item", err)
+	}
+	if aws.StringValue(result.Item["Mutant"].N) != "3" || aws.StringValue(result.Item["dna_type"].S) != "Mutant" {
+		t.Error("Expected the item created with its key and the sum. Got:", result.Item)
+	}
+}
+
+func TestUpdateItemUnsupportedExpression(t *testing.T) {
+	db := NewDynamoDB(Tables)
+	_, err := db.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:        aws.String("stats"),
+		Key:              Item{"dna_type": {S: aws.String("Mutant")}},
+		UpdateExpression: aws.String("SET a = :a"),
+	})
+	if err == nil {
+		t.Error("Expected error for a SET expression")
+	}
+}
+
+func TestPutItemCondition(t *testing.T) {
+	db := NewDynamoDB(Tables)
+	input := &dynamodb.PutItemInput{
+		TableName:                aws.String("dnas"),
+		Item:                     key("1"),
+		ConditionExpression:      aws.String("attribute_not_exists(#uuid)"),
+		ExpressionAttributeNames: map[string]*string{"#uuid": aws.String("uuid")},
+	}
+	_, err := db.PutItem(input)
+	if err != nil {
+		t.Error("No error expected putting the item", err)
+	}
+	_, err = db.PutItem(input)
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+		t.Error("Expected conditional check failed. Got:", err)
+	}
+}
+
+func TestUnknownTable(t *testing.T) {
+	db := NewDynamoDB(Tables)
+	_, err := db.GetItem(&dynamodb.GetItemInput{TableName: aws.String("other"), Key: key("1")})
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodb.ErrCodeResourceNotFoundException {
+		t.Error("Expected resource not found. Got:", err)
+	}
+}
+
+func TestItemsAreCopied(t *testing.T) {
+	db := NewDynamoDB(Tables)
+	item := key("1")
+	db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("dnas"), Item: item})
+	item["type"] = &dynamodb.AttributeValue{S: aws.String("Mutant")}
+	result, _ := db.GetItem(&dynamodb.GetItemInput{TableName: aws.String("dnas"), Key: key("1")})
+	if result.Item["type"] != nil {
+		t.Error("Expected the stored item not to change. Got:", result.Item)
+	}
+}
+
+func TestScanSegments(t *testing.T) {
+	db := NewDynamoDB(Tables)
+	for _, id := range []string{"c", "a", "b", "d", "e"} {
+		db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("dnas"), Item: key(id)})
+	}
+	result, err := db.Scan(&dynamodb.ScanInput{TableName: aws.String("dnas")})
+	if err != nil || len(result.Items) != 5 || aws.StringValue(result.Items[0]["uuid"].S) != "a" {
+		t.Error("Expected every item sorted by key. Got:", result, err)
+	}
+	total := 0
+	for segment := int64(0); segment < 3; segment++ {
+		result, _ := db.Scan(&dynamodb.ScanInput{
+			TableName:     aws.String("dnas"),
+			Segment:       aws.Int64(segment),
+			TotalSegments: aws.Int64(3),
+		})
+		total += len(result.Items)
+	}
+	if total != 5 {
+		t.Error("Expected the segments to split the items. Got:", total)
+	}
+}
+
+func TestBatchGetItem(t *testing.T) {
+	db := NewDynamoDB(Tables)
+	db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("dnas"), Item: key("1")})
+	result, err := db.BatchGetItem(&dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			"dnas": {Keys: []Item{key("1"), key("2")}},
+		},
+	})
+	if err != nil || len(result.Responses["dnas"]) != 1 || len(result.UnprocessedKeys) != 0 {
+		t.Error("Expected only the existing item. Got:", result, err)
+	}
+}
+
+func TestTransactWriteItemsIsAtomic(t *testing.T) {
+	db := NewDynamoDB(Tables)
+	db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("dnas"), Item: key("1")})
+	_, err := db.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Update: &dynamodb.Update{
+				TableName:                 aws.String("stats"),
+				Key:                       Item{"dna_type": {S: aws.String("Mutant")}},
+				ExpressionAttributeValues: Item{":inc": {N: aws.String("1")}},
+				UpdateExpression:          aws.String("ADD type_count :inc"),
+			}},
+			{Put: &dynamodb.Put{
+				TableName:           aws.String("dnas"),
+				Item:                key("1"),
+				ConditionExpression: aws.String("attribute_not_exists(uuid)"),
+			}},
+		},
+	})
+	canceled, ok := err.(*dynamodb.TransactionCanceledException)
+	if !ok || len(canceled.CancellationReasons) != 2 {
+		t.Error("Expected the transaction to be canceled. Got:", err)
+		return
+	}
+	if aws.StringValue(canceled.CancellationReasons[0].Code) != "None" || aws.StringValue(canceled.CancellationReasons[1].Code) != "ConditionalCheckFailed" {
+		t.Error("Expected the reason of each item. Got:", canceled.CancellationReasons)
+	}
+	result, _ := db.Scan(&dynamodb.ScanInput{TableName: aws.String("stats")})
+	if len(result.Items) != 0 {
+		t.Error("Expected no writes from a canceled transaction. Got:", result.Items)
+	}
+}
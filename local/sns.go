@@ -0,0 +1,79 @@
+package local
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/google/uuid"
+)
+
+type Subscriber func(events.SNSEvent) error
+
+// Bus delivers every published message to the subscribers before Publish
+// returns, as the event SNS would send to a lambda. Like SNS, the publisher
+// does not get the errors of the subscribers, they are only logged
+type Bus struct {
+	snsiface.SNSAPI
+	mu          sync.Mutex
+	subscribers []Subscriber
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) AddSubscriber(subscriber Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, subscriber)
+}
+
+func (b *Bus) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	if input.Message == nil {
+		return nil, errors.New("missing message")
+	}
+	messageId := uuid.New().String()
+	attributes := map[string]interface{}{}
+	for name, value := range input.MessageAttributes {
+		attributes[name] = map[string]interface{}{
+			"Type":  aws.StringValue(value.DataType),
+			"Value": aws.StringValue(value.StringValue),
+		}
+	}
+	event := events.SNSEvent{
+		Records: []events.SNSEventRecord{
+			{
+				EventSource:          "local:sns",
+				EventSubscriptionArn: aws.StringValue(input.TopicArn),
+				SNS: events.SNSEntity{
+					MessageID: messageId,
+					TopicArn:  aws.StringValue(input.TopicArn),
+					Subject:   aws.StringValue(input.Subject),
+					Message:   *input.Message,
+					Timestamp: time.Now(),
+					Type:      "Notification",
+
+					MessageAttributes: attributes,
+				},
+			},
+		},
+	}
+	b.mu.Lock()
+	subscribers := append([]Subscriber{}, b.subscribers...)
+	b.mu.Unlock()
+	for _, subscriber := range subscribers {
+		err := subscriber(event)
+		if err != nil {
+			log.Printf("Got error delivering message %s: %s", messageId, err)
+		}
+	}
+	return &sns.PublishOutput{
+		MessageId: aws.String(messageId),
+	}, nil
+}
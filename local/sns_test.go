@@ -0,0 +1,30 @@
+package local
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+func TestPublishDelivers(t *testing.T) {
+	bus := NewBus()
+	received := []events.SNSEvent{}
+	bus.AddSubscriber(func(event events.SNSEvent) error {
+		received = append(received, event)
+		return errors.New("failed")
+	})
+	output, err := bus.Publish(&sns.PublishInput{Message: aws.String("message")})
+	if err != nil {
+		t.Error("Expected the errors of the subscribers not to reach the publisher", err)
+	}
+	if len(received) != 1 || received[0].Records[0].SNS.Message != "message" {
+		t.Error("Expected the message to be delivered. Got:", received)
+		return
+	}
+	if received[0].Records[0].SNS.MessageID != aws.StringValue(output.MessageId) {
+		t.Error("Expected the message id of the output. Got:", received[0].Records[0].SNS.MessageID)
+	}
+}
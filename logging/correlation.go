@@ -0,0 +1,28 @@
+package logging
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CORRELATION_ID is the field of the logs, the message and its attributes that
+// follows a dna from its analysis to its saving
+const CORRELATION_ID = "correlation_id"
+
+const CORRELATION_ID_HEADER = "X-Correlation-Id"
+
+// INVOCATION_ID_HEADER carries the id of the lambda request to the handlers,
+// which only get the API request
+const INVOCATION_ID_HEADER = "X-Lambda-Request-Id"
+
+// CorrelationId is the one the client sent in the X-Correlation-Id header, or
+// a new one
+func CorrelationId(headers map[string]string) string {
+	for name, value := range headers {
+		if strings.EqualFold(name, CORRELATION_ID_HEADER) && value != "" {
+			return value
+		}
+	}
+	return uuid.New().String()
+}
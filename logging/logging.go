@@ -0,0 +1,243 @@
+// Package logging writes the logs as JSON lines, with the id of the lambda
+// request and the fields of each entry, so they can be queried in CloudWatch
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+type Level int
+
+const REQUEST_ID = "request_id"
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+)
+
+var levelNames = map[Level]string{
+	DEBUG: "debug",
+	INFO:  "info",
+	WARN:  "warn",
+	ERROR: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	if strings.EqualFold(name, "warning") {
+		return WARN, nil
+	}
+	return INFO, fmt.Errorf("unknown log level %s", name)
+}
+
+// LevelFromEnv is the level of LOG_LEVEL, or of AWS_LAMBDA_LOG_LEVEL, which the
+// lambda sets from its logging configuration. Each function can set its own.
+// It is INFO by default
+func LevelFromEnv() Level {
+	for _, name := range []string{"LOG_LEVEL", "AWS_LAMBDA_LOG_LEVEL"} {
+		if value := os.Getenv(name); value != "" {
+			level, err := ParseLevel(value)
+			if err == nil {
+				return level
+			}
+		}
+	}
+	return INFO
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// Logger writes an entry per line with its time, level and message, then the
+// fields of the logger and the entry
+type Logger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  Level
+	fields []field
+	Now    func() time.Time
+}
+
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{
+		mu:    &sync.Mutex{},
+		out:   out,
+		level: level,
+		Now:   time.Now,
+	}
+}
+
+// With returns a logger that adds the key value pairs to every entry
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	with := *l
+	with.fields = append(append([]field{}, l.fields...), Fields(keyvals)...)
+	return &with
+}
+
+// WithRequestId returns a logger that adds the id of the request to every
+// entry, or the same logger when there is no id
+func (l *Logger) WithRequestId(requestId string) *Logger {
+	if requestId == "" {
+		return l
+	}
+	return l.With(REQUEST_ID, requestId)
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.Log(DEBUG, msg, keyvals...)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.Log(INFO, msg, keyvals...)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.Log(WARN, msg, keyvals...)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.Log(ERROR, msg, keyvals...)
+}
+
+func (l *Logger) Log(level Level, msg string, keyvals ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	fields := []field{
+		{"time", l.Now().UTC().Format(time.RFC3339Nano)},
+		{"level", level.String()},
+		{"msg", msg},
+	}
+	fields = append(fields, l.fields...)
+	fields = append(fields, Fields(keyvals)...)
+	line := Encode(fields)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(line)
+}
+
+// Fields pairs the keys with their values. Errors are logged by their
+// message, and a key without value gets null
+func Fields(keyvals []interface{}) []field {
+	fields := []field{}
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		var value interface{}
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		fields = append(fields, field{key, value})
+	}
+	return fields
+}
+
+// Encode writes the fields as a JSON object in their order. A later field
+// replaces an earlier one with the same key
+func Encode(fields []field) []byte {
+	index := map[string]int{}
+	unique := []field{}
+	for _, f := range fields {
+		if i, ok := index[f.key]; ok {
+			unique[i] = f
+			continue
+		}
+		index[f.key] = len(unique)
+		unique = append(unique, f)
+	}
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range unique {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(f.value))
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+var (
+	mu  sync.Mutex
+	std = New(os.Stderr, INFO)
+)
+
+func Default() *Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	return std
+}
+
+func SetDefault(l *Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	std = l
+}
+
+// StartInvocation returns the default logger with the id of the lambda request
+// in the context, for the entries of the invocation
+func StartInvocation(ctx context.Context) *Logger {
+	return Default().WithRequestId(InvocationId(ctx))
+}
+
+// InvocationId is the id of the lambda request in the context, if it has one
+func InvocationId(ctx context.Context) string {
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		return lc.AwsRequestID
+	}
+	return ""
+}
+
+// Setup makes the default logger write JSON with the level of the environment,
+// and sends the standard log through it
+func Setup() {
+	SetDefault(New(os.Stderr, LevelFromEnv()))
+	log.SetFlags(0)
+	log.SetOutput(StdWriter{})
+}
+
+// StdWriter turns the lines of the standard log into error entries of the
+// default logger. The packages only use the standard log for the errors of
+// the calls they return them from, and the logger for everything else
+type StdWriter struct{}
+
+func (w StdWriter) Write(p []byte) (int, error) {
+	Default().Error(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+func NewTestLogger(level Level) (*Logger, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	logger := New(out, level)
+	logger.Now = func() time.Time {
+		return time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	return logger, out
+}
+
+func TestLogWritesJSONLine(t *testing.T) {
+	logger, out := NewTestLogger(INFO)
+	logger.Info("Saved dna", "uuid", "1", "error", errors.New("failed"))
+	expected := `{"time":"2021-01-02T03:04:05Z","level":"info","msg":"Saved dna","uuid":"1","error":"failed"}` + "\n"
+	if out.String() != expected {
+		t.Error("Expected the entry as a JSON line. Got:", out.String())
+	}
+}
+
+func TestLogSkipsLowerLevels(t *testing.T) {
+	logger, out := NewTestLogger(WARN)
+	logger.Info("Saved dna")
+	logger.Debug("Published dna")
+	if out.Len() != 0 {
+		t.Error("Expected no entries below warn. Got:", out.String())
+	}
+	logger.Error("Got error saving dna")
+	if !strings.Contains(out.String(), `"level":"error"`) {
+		t.Error("Expected the error entry. Got:", out.String())
+	}
+}
+
+func TestWithAddsFields(t *testing.T) {
+	logger, out := NewTestLogger(INFO)
+	logger.With("correlation_id", "abc").Info("Saved dna", "uuid", "1")
+	logger.Info("Analyzed dna")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	entry := map[string]interface{}{}
+	json.Unmarshal([]byte(lines[0]), &entry)
+	if entry["correlation_id"] != "abc" || entry["uuid"] != "1" {
+		t.Error("Expected the fields of the logger and the entry. Got:", lines[0])
+	}
+	if strings.Contains(lines[1], "correlation_id") {
+		t.Error("Expected the logger to keep its fields. Got:", lines[1])
+	}
+}
+
+func TestEncodeReplacesRepeatedKeys(t *testing.T) {
+	line := string(Encode(Fields([]interface{}{"a", 1, "b", 2, "a", 3})))
+	if line != `{"a":3,"b":2}`+"\n" {
+		t.Error("Expected the last value of a repeated key. Got:", line)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("DEBUG")
+	if level != DEBUG || err != nil {
+		t.Error("Expected the debug level. Got:", level, err)
+	}
+	_, err = ParseLevel("verbose")
+	if err == nil {
+		t.Error("Expected error for an unknown level")
+	}
+}
+
+func TestLevelFromEnv(t *testing.T) {
+	os.Setenv("AWS_LAMBDA_LOG_LEVEL", "ERROR")
+	defer os.Unsetenv("AWS_LAMBDA_LOG_LEVEL")
+	if level := LevelFromEnv(); level != ERROR {
+		t.Error("Expected the level of the lambda. Got:", level)
+	}
+	os.Setenv("LOG_LEVEL", "debug")
+	defer os.Unsetenv("LOG_LEVEL")
+	if level := LevelFromEnv(); level != DEBUG {
+		t.Error("Expected LOG_LEVEL to take precedence. Got:", level)
+	}
+}
+
+func TestStartInvocationAddsRequestId(t *testing.T) {
+	logger, out := NewTestLogger(INFO)
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+		AwsRequestID: "request-1",
+	})
+	previous := Default()
+	SetDefault(logger)
+	defer SetDefault(previous)
+	StartInvocation(ctx).Info("Saved dna")
+	if !strings.Contains(out.String(), `"request_id":"request-1"`) {
+		t.Error("Expected the id of the request. Got:", out.String())
+	}
+	out.Reset()
+	StartInvocation(context.Background()).Info("Saved dna")
+	Default().Info("Saved dna")
+	if strings.Contains(out.String(), "request_id") {
+		t.Error("Expected the id to stay in the logger of its invocation. Got:", out.String())
+	}
+}
+
+func TestStdWriterLogsErrors(t *testing.T) {
+	logger, out := NewTestLogger(INFO)
+	previous := Default()
+	SetDefault(logger)
+	defer SetDefault(previous)
+	std := log.New(StdWriter{}, "", 0)
+	std.Printf("Got error calling PutItem: %s", "throttled")
+	entry := map[string]interface{}{}
+	json.Unmarshal(out.Bytes(), &entry)
+	if entry["level"] != "error" || entry["msg"] != "Got error calling PutItem: throttled" {
+		t.Error("Expected an error entry. Got:", out.String())
+	}
+}
+
+func TestCorrelationIdFromHeader(t *testing.T) {
+	correlationId := CorrelationId(map[string]string{"x-correlation-id": "abc"})
+	if correlationId != "abc" {
+		t.Error("Expected the correlation id of the header. Got:", correlationId)
+	}
+	if CorrelationId(map[string]string{}) == "" {
+		t.Error("Expected a new correlation id")
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/server"
+)
+
+const (
+	DEFAULT_CORS_METHODS = "GET, POST, OPTIONS"
+	DEFAULT_CORS_HEADERS = "Content-Type, If-None-Match, X-Message-Id, X-Correlation-Id"
+	// DEFAULT_CORS_EXPOSED_HEADERS are the headers of the responses the
+	// browsers let the scripts read
+	DEFAULT_CORS_EXPOSED_HEADERS = "ETag, X-Dna-Uuid, X-Correlation-Id"
+	DEFAULT_CORS_MAX_AGE         = 600
+)
+
+// CORSConfig has the origins allowed to call the API from a browser. Without
+// origins CORS is disabled
+type CORSConfig struct {
+	AllowedOrigins []string
+	AllowedMethods string
+	AllowedHeaders string
+	ExposedHeaders string
+	MaxAge         int
+}
+
+// CORSConfigFromEnv reads the comma separated origins of CORS_ALLOWED_ORIGINS,
+// which can be *, and the max age of the preflights in seconds of
+// CORS_MAX_AGE
+func CORSConfigFromEnv() CORSConfig {
+	config := CORSConfig{
+		AllowedMethods: DEFAULT_CORS_METHODS,
+		AllowedHeaders: DEFAULT_CORS_HEADERS,
+		ExposedHeaders: DEFAULT_CORS_EXPOSED_HEADERS,
+		MaxAge:         DEFAULT_CORS_MAX_AGE,
+	}
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			config.AllowedOrigins = append(config.AllowedOrigins, origin)
+		}
+	}
+	if maxAge, err := strconv.Atoi(os.Getenv("CORS_MAX_AGE")); err == nil && maxAge >= 0 {
+		config.MaxAge = maxAge
+	}
+	return config
+}
+
+// AllowedOrigin is the value of the Access-Control-Allow-Origin header for the
+// origin, or empty when it is not allowed
+func (c CORSConfig) AllowedOrigin(origin string) string {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if origin != "" && allowed == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+// CORS adds the CORS headers to the responses to allowed origins, and answers
+// their preflights with 204 without calling the handler
+func CORS(config CORSConfig) Middleware {
+	return func(next server.Handler) server.Handler {
+		if len(config.AllowedOrigins) == 0 {
+			return next
+		}
+		return func(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			origin := config.AllowedOrigin(Header(req, "Origin"))
+			if origin == "" {
+				return next(req)
+			}
+			if req.HTTPMethod == http.MethodOptions && Header(req, "Access-Control-Request-Method") != "" {
+				response := events.APIGatewayProxyResponse{
+					StatusCode: http.StatusNoContent,
+				}
+				SetCORSHeaders(&response, origin)
+				SetHeader(&response, "Access-Control-Allow-Methods", config.AllowedMethods)
+				SetHeader(&response, "Access-Control-Allow-Headers", config.AllowedHeaders)
+				SetHeader(&response, "Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+				return response, nil
+			}
+			response, err := next(req)
+			if err != nil {
+				return response, err
+			}
+			SetCORSHeaders(&response, origin)
+			if config.ExposedHeaders != "" {
+				SetHeader(&response, "Access-Control-Expose-Headers", config.ExposedHeaders)
+			}
+			return response, nil
+		}
+	}
+}
+
+// SetCORSHeaders allows the origin. Unless every origin is allowed, the
+// response varies by origin for the caches
+func SetCORSHeaders(response *events.APIGatewayProxyResponse, origin string) {
+	SetHeader(response, "Access-Control-Allow-Origin", origin)
+	if origin != "*" {
+		SetHeader(response, "Vary", "Origin")
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var config = CORSConfig{
+	AllowedOrigins: []string{"https://magneto.example"},
+	AllowedMethods: DEFAULT_CORS_METHODS,
+	AllowedHeaders: DEFAULT_CORS_HEADERS,
+	ExposedHeaders: DEFAULT_CORS_EXPOSED_HEADERS,
+	MaxAge:         60,
+}
+
+func TestCORSPreflight(t *testing.T) {
+	called := false
+	handler := func(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		called = true
+		return ok(req)
+	}
+	response, _ := CORS(config)(handler)(events.APIGatewayProxyRequest{
+		HTTPMethod: "OPTIONS",
+		Headers:    map[string]string{"origin": "https://magneto.example", "access-control-request-method": "POST"},
+	})
+	if called || response.StatusCode != 204 {
+		t.Error("Expected the preflight to be answered without the handler. Got:", response.StatusCode, called)
+	}
+	if response.Headers["Access-Control-Allow-Origin"] != "https://magneto.example" || response.Headers["Access-Control-Allow-Methods"] != DEFAULT_CORS_METHODS || response.Headers["Access-Control-Max-Age"] != "60" || response.Headers["Vary"] != "Origin" {
+		t.Error("Expected the preflight headers. Got:", response.Headers)
+	}
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	response, _ := CORS(config)(ok)(events.APIGatewayProxyRequest{
+		HTTPMethod: "GET",
+		Headers:    map[string]string{"Origin": "https://magneto.example"},
+	})
+	if response.Body != "ok" || response.Headers["Access-Control-Allow-Origin"] != "https://magneto.example" || response.Headers["Access-Control-Expose-Headers"] != DEFAULT_CORS_EXPOSED_HEADERS {
+		t.Error("Expected the response with the CORS headers. Got:", response)
+	}
+}
+
+func TestCORSOtherOrigin(t *testing.T) {
+	response, _ := CORS(config)(ok)(events.APIGatewayProxyRequest{
+		HTTPMethod: "OPTIONS",
+		Headers:    map[string]string{"Origin": "https://other.example", "Access-Control-Request-Method": "POST"},
+	})
+	if response.Body != "ok" || response.Headers["Access-Control-Allow-Origin"] != "" {
+		t.Error("Expected other origins to reach the handler without CORS headers. Got:", response)
+	}
+}
+
+func TestCORSConfigFromEnv(t *testing.T) {
+	os.Setenv("CORS_ALLOWED_ORIGINS", "*, https://magneto.example")
+	os.Setenv("CORS_MAX_AGE", "30")
+	defer os.Unsetenv("CORS_ALLOWED_ORIGINS")
+	defer os.Unsetenv("CORS_MAX_AGE")
+	config := CORSConfigFromEnv()
+	if len(config.AllowedOrigins) != 2 || config.MaxAge != 30 || config.AllowedOrigin("https://any.example") != "*" {
+		t.Error("Expected the origins and max age of the environment. Got:", config)
+	}
+}
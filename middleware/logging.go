@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/server"
+)
+
+// Logging logs every request with its id, the status of its response, how long
+// it took, the correlation id the client sent and the id of its trace, if any
+func Logging(next server.Handler) server.Handler {
+	return func(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		start := time.Now()
+		response, err := next(req)
+		elapsed := time.Since(start)
+		logger := Logger(req).With(
+			"method", req.HTTPMethod,
+			"path", req.Path,
+			"source_ip", req.RequestContext.Identity.SourceIP,
+			"duration_ms", float64(elapsed)/float64(time.Millisecond),
+		)
+		if correlationId := Header(req, logging.CORRELATION_ID_HEADER); correlationId != "" {
+			logger = logger.With(logging.CORRELATION_ID, correlationId)
+		}
+		if traceId := TraceId(req); traceId != "" {
+			logger = logger.With("trace_id", traceId)
+		}
+		if err != nil {
+			logger.Error("Got error handling request", "error", err)
+			return response, err
+		}
+		logger.Info("Handled request", "status", response.StatusCode, "bytes_in", len(req.Body), "bytes_out", len(response.Body))
+		return response, nil
+	}
+}
+
+// Timing tells the client how long the handler took, in milliseconds, with
+// the Server-Timing header
+func Timing(next server.Handler) server.Handler {
+	return func(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		start := time.Now()
+		response, err := next(req)
+		if err == nil {
+			SetHeader(&response, "Server-Timing", FormatTiming(time.Since(start)))
+		}
+		return response, err
+	}
+}
+
+func FormatTiming(elapsed time.Duration) string {
+	return fmt.Sprintf("handler;dur=%.3f", float64(elapsed)/float64(time.Millisecond))
+}
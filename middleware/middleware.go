@@ -0,0 +1,61 @@
+// Package middleware wraps the handlers with the behavior every one of them
+// needs, like recovering from panics, logging and CORS
+package middleware
+
+import (
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/server"
+)
+
+type Middleware func(server.Handler) server.Handler
+
+// Chain wraps the handler with the middlewares, the first one outermost
+func Chain(handler server.Handler, middlewares ...Middleware) server.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Default traces, logs and times every request, answers the CORS preflights
+// and turns panics into a 500 that is logged as such. Recover is outermost,
+// so the panics of the other middlewares are answered too, and also wraps
+// the handler, so its 500 is still traced, logged and timed
+func Default(handler server.Handler) server.Handler {
+	return Chain(handler, Recover, Tracing, Logging, Timing, CORS(CORSConfigFromEnv()), Recover)
+}
+
+// Header gets a header of the request regardless of its case, which REST
+// APIs keep and HTTP APIs lower
+func Header(req events.APIGatewayProxyRequest, name string) string {
+	for key, value := range req.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// RequestId is the id of the lambda request the adapter sets in the headers,
+// or else the id of the API request, which the serve commands generate
+func RequestId(req events.APIGatewayProxyRequest) string {
+	if id := Header(req, logging.INVOCATION_ID_HEADER); id != "" {
+		return id
+	}
+	return req.RequestContext.RequestID
+}
+
+// Logger is the default logger with the id of the request
+func Logger(req events.APIGatewayProxyRequest) *logging.Logger {
+	return logging.Default().WithRequestId(RequestId(req))
+}
+
+func SetHeader(response *events.APIGatewayProxyResponse, name string, value string) {
+	if response.Headers == nil {
+		response.Headers = map[string]string{}
+	}
+	response.Headers[name] = value
+}
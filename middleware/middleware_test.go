@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/detector"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/server"
+)
+
+func ok(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: "ok"}, nil
+}
+
+func TestChainOrder(t *testing.T) {
+	calls := []string{}
+	named := func(name string) Middleware {
+		return func(next server.Handler) server.Handler {
+			return func(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				calls = append(calls, name)
+				return next(req)
+			}
+		}
+	}
+	Chain(ok, named("first"), named("second"))(events.APIGatewayProxyRequest{})
+	if strings.Join(calls, ",") != "first,second" {
+		t.Error("Expected the first middleware outermost. Got:", calls)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	detect := func(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		detector.IsMutant([]string{"AAAA", "C", "G", "T"})
+		return ok(req)
+	}
+	req := events.APIGatewayProxyRequest{
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID: "request",
+		},
+	}
+	response, err := Recover(detect)(req)
+	if err != nil || response.StatusCode != 500 {
+		t.Fatal("500 - Internal Server Error http status code expected. Got:", response.StatusCode, err)
+	}
+	body := ErrorBody{}
+	json.Unmarshal([]byte(response.Body), &body)
+	if body.Error != "Internal Server Error" || body.RequestId != "request" || response.Headers["Content-Type"] != "application/json" {
+		t.Error("Expected a json error with the request id. Got:", response.Body, response.Headers)
+	}
+}
+
+func TestTiming(t *testing.T) {
+	response, _ := Timing(ok)(events.APIGatewayProxyRequest{})
+	if !strings.HasPrefix(response.Headers["Server-Timing"], "handler;dur=") {
+		t.Error("Expected the Server-Timing header. Got:", response.Headers)
+	}
+}
+
+func TestDefaultLogsRecoveredPanic(t *testing.T) {
+	panics := func(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		panic("boom")
+	}
+	response, err := Default(panics)(events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/stats"})
+	if err != nil || response.StatusCode != 500 || response.Headers["Server-Timing"] == "" {
+		t.Error("Expected a timed 500. Got:", response, err)
+	}
+}
+
+func TestLoggingHasTheIdOfEachRequest(t *testing.T) {
+	out := &bytes.Buffer{}
+	previous := logging.Default()
+	logging.SetDefault(logging.New(out, logging.INFO))
+	defer logging.SetDefault(previous)
+	req := events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/stats"}
+	req.RequestContext.RequestID = "request-1"
+	Logging(ok)(req)
+	req.RequestContext.RequestID = "request-2"
+	Logging(ok)(req)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"request_id":"request-1"`) || !strings.Contains(lines[1], `"request_id":"request-2"`) {
+		t.Error("Expected every entry to have the id of its request. Got:", out.String())
+	}
+}
+
+func TestLoggingPrefersTheIdOfTheLambdaRequest(t *testing.T) {
+	out := &bytes.Buffer{}
+	previous := logging.Default()
+	logging.SetDefault(logging.New(out, logging.INFO))
+	defer logging.SetDefault(previous)
+	req := events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/stats", Headers: map[string]string{"x-lambda-request-id": "invocation"}}
+	req.RequestContext.RequestID = "request"
+	Logging(ok)(req)
+	if !strings.Contains(out.String(), `"request_id":"invocation"`) {
+		t.Error("Expected the entry to have the id of the lambda request. Got:", out.String())
+	}
+}
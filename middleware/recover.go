@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/server"
+)
+
+type ErrorBody struct {
+	Error     string `json:"error"`
+	RequestId string `json:"request_id,omitempty"`
+}
+
+// Recover answers a panic of the handler with a 500 that has the id of the
+// request, instead of failing the invocation, and logs it with its stack
+func Recover(next server.Handler) server.Handler {
+	return func(req events.APIGatewayProxyRequest) (response events.APIGatewayProxyResponse, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				Logger(req).Error("Got panic handling request",
+					"method", req.HTTPMethod,
+					"path", req.Path,
+					"panic", fmt.Sprint(recovered),
+					"stack", string(debug.Stack()),
+				)
+				response, err = RespondError(http.StatusInternalServerError, RequestId(req))
+			}
+		}()
+		return next(req)
+	}
+}
+
+func RespondError(status int, requestId string) (events.APIGatewayProxyResponse, error) {
+	body, _ := json.Marshal(ErrorBody{
+		Error:     http.StatusText(status),
+		RequestId: requestId,
+	})
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(body),
+	}, nil
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/server"
+	"github.com/fpinatares/magneto/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing makes a server span of every request, which continues the trace of
+// the traceparent header when the client sent one. The handler gets the trace
+// context of the span in the headers of the request, to make its spans
+// children of it with tracing.Extract
+func Tracing(next server.Handler) server.Handler {
+	return func(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		route := req.Resource
+		if route == "" {
+			route = req.Path
+		}
+		ctx, span := tracing.Tracer().Start(tracing.Extract(req.Headers), req.HTTPMethod+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", req.HTTPMethod),
+				attribute.String("http.route", route),
+				attribute.String("url.path", req.Path),
+				attribute.String("client.address", req.RequestContext.Identity.SourceIP),
+			),
+		)
+		req.Headers = tracing.Inject(ctx, req.Headers)
+		response, err := next(req)
+		span.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode))
+		if err == nil && response.StatusCode >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+		tracing.End(span, err)
+		return response, err
+	}
+}
+
+// TraceId is the id of the trace of the request, if it has one
+func TraceId(req events.APIGatewayProxyRequest) string {
+	spanContext := trace.SpanContextFromContext(tracing.Extract(req.Headers))
+	if !spanContext.IsValid() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
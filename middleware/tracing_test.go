@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const TRACEPARENT = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTracingContinuesTheTraceOfTheClient(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	traceId := ""
+	handler := Tracing(func(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		traceId = TraceId(req)
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}, nil
+	})
+	handler(events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/mutant",
+		Headers:    map[string]string{"Traceparent": TRACEPARENT},
+	})
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatal("Expected the span of the request. Got:", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "POST /mutant" || span.SpanKind() != trace.SpanKindServer {
+		t.Error("Expected a server span named by the route. Got:", span.Name(), span.SpanKind())
+	}
+	if span.Parent().SpanID().String() != "00f067aa0ba902b7" || traceId != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Error("Expected the trace of the client. Got:", span.Parent(), traceId)
+	}
+	if span.Status().Code != codes.Error {
+		t.Error("Expected a 500 to be an error. Got:", span.Status())
+	}
+}
+
+func TestTraceIdWithoutTrace(t *testing.T) {
+	traceId := TraceId(events.APIGatewayProxyRequest{})
+	if traceId != "" {
+		t.Error("Expected no trace id. Got:", traceId)
+	}
+}
+
+type panicTracerProvider struct {
+	noop.TracerProvider
+}
+
+func (p panicTracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return panicTracer{}
+}
+
+type panicTracer struct {
+	noop.Tracer
+}
+
+func (t panicTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	panic("tracer failed")
+}
+
+func TestDefaultRecoversPanicOfTheMiddlewares(t *testing.T) {
+	otel.SetTracerProvider(panicTracerProvider{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	response, err := Default(ok)(events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/stats"})
+	if err != nil || response.StatusCode != http.StatusInternalServerError {
+		t.Error("500 - Internal Server Error http status code expected. Got:", response.StatusCode, err)
+	}
+}
@@ -0,0 +1,164 @@
+package mutant
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/fpinatares/magneto/detector"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/middleware"
+	"github.com/fpinatares/magneto/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const STATS_TABLE = "stats"
+const DNAS_TABLE = "dnas"
+
+// DNA_UUID_HEADER has the uuid of the analyzed dna, to look it up later
+const DNA_UUID_HEADER = "X-Dna-Uuid"
+
+var EnumDnaType = detector.EnumDnaType
+
+type DnaData = detector.DnaData
+
+// TOPIC_ARN is where the analyzed dnas are published to be saved
+const TOPIC_ARN = "arn:aws:sns:us-east-1:870963517916:save-dna"
+
+// Message is what the detector publishes, the analyzed dna with the id that
+// correlates the logs of its analysis and its saving, and when it was
+// submitted, to measure how long it takes to reach the stats
+type Message struct {
+	DnaData
+	CorrelationId string `json:"correlation_id,omitempty"`
+	SubmittedAt   string `json:"submitted_at,omitempty"`
+}
+
+type Handler struct {
+	notifier snsiface.SNSAPI
+}
+
+func NewHandler(notifier snsiface.SNSAPI) *Handler {
+	return &Handler{
+		notifier: notifier,
+	}
+}
+
+func (d *Handler) DetectMutant(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if req.Headers["content-type"] != "application/json" && req.Headers["Content-Type"] != "application/json" {
+		return Respond(http.StatusNotAcceptable)
+	}
+	submittedAt := time.Now()
+	ctx := tracing.Extract(req.Headers)
+	_, span := tracing.Tracer().Start(ctx, "detect")
+	dnaData, err := ParseRequest(req.Body)
+	if err != nil {
+		tracing.End(span, err)
+		return Respond(http.StatusBadRequest)
+	}
+	dnaData.Uuid = uuid.New().String()
+	dnaData.Type = detector.GetDnaType(dnaData.Dna)
+	span.SetAttributes(
+		attribute.String("dna.uuid", dnaData.Uuid),
+		attribute.String("dna.type", dnaData.Type),
+		attribute.Int("dna.size", len(dnaData.Dna)),
+	)
+	span.End()
+	correlationId := logging.CorrelationId(req.Headers)
+	logger := middleware.Logger(req).With(logging.CORRELATION_ID, correlationId, "uuid", dnaData.Uuid)
+	logger.Info("Analyzed dna", "type", dnaData.Type, "size", len(dnaData.Dna))
+	json, _ := json.Marshal(Message{
+		DnaData:       dnaData,
+		CorrelationId: correlationId,
+		SubmittedAt:   submittedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	output, err := d.Publish(ctx, &sns.PublishInput{
+		Message:  aws.String(string(json)),
+		TopicArn: aws.String(TOPIC_ARN),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			logging.CORRELATION_ID: {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(correlationId),
+			},
+		},
+	})
+	if err != nil {
+		logger.Error("Got error publishing dna", "error", err)
+	} else if output != nil {
+		logger.Debug("Published dna", "message_id", aws.StringValue(output.MessageId))
+	}
+	status := http.StatusOK
+	if dnaData.Type != EnumDnaType.Mutant {
+		status = http.StatusForbidden
+	}
+	response, err := Respond(status)
+	response.Headers = map[string]string{
+		DNA_UUID_HEADER:               dnaData.Uuid,
+		logging.CORRELATION_ID_HEADER: correlationId,
+	}
+	return response, err
+}
+
+// Publish makes a producer span of the publishing, whose trace context goes in
+// the attributes of the message, so the saving continues the trace
+func (d *Handler) Publish(ctx context.Context, input *sns.PublishInput) (*sns.PublishOutput, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "save-dna publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "aws_sns"),
+			attribute.String("messaging.destination.name", aws.StringValue(input.TopicArn)),
+		),
+	)
+	tracing.InjectAttributes(ctx, input)
+	output, err := d.notifier.Publish(input)
+	if output != nil {
+		span.SetAttributes(attribute.String("messaging.message.id", aws.StringValue(output.MessageId)))
+	}
+	tracing.End(span, err)
+	return output, err
+}
+
+func ParseRequest(body string) (DnaData, error) {
+	dnaData := new(DnaData)
+	err := json.Unmarshal([]byte(body), &dnaData)
+	if err != nil {
+		log.Printf("Got error calling Unmarshal: %s", err)
+		return *dnaData, err
+	}
+	err = detector.ValidateMatrix(dnaData.Dna)
+	if err != nil {
+		log.Printf("Got error calling ValidateMatrix: %s", err)
+		return *dnaData, err
+	}
+	err = detector.ValidateDna(dnaData.Dna)
+	if err != nil {
+		log.Printf("Got error calling ValidateDna: %s", err)
+		return *dnaData, err
+	}
+	return *dnaData, nil
+}
+
+func GetSNSClient() *sns.SNS {
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	svc := sns.New(sess)
+	return svc
+}
+
+func Respond(status int) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Body:       http.StatusText(status),
+	}, nil
+}
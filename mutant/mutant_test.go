@@ -0,0 +1,216 @@
+package mutant
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/fpinatares/magneto/logging"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type mockSNSClient struct {
+	snsiface.SNSAPI
+}
+
+func (m *mockSNSClient) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	return nil, nil
+}
+
+func TestDetectMutantWithNotAcceptableContentType(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{},
+	}
+	req.Headers["content-type"] = "application/xml"
+	d := Handler{
+		notifier: &mockSNSClient{},
+	}
+	response, _ := d.DetectMutant(req)
+	if response.StatusCode != 406 {
+		t.Error("406 - Not Acceptable http status code expected. Got:", response.StatusCode)
+	}
+}
+
+func TestDetectMutantWithEmptyBody(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{},
+		Body:    "",
+	}
+	req.Headers["content-type"] = "application/json"
+	d := Handler{
+		notifier: &mockSNSClient{},
+	}
+	response, _ := d.DetectMutant(req)
+	if response.StatusCode != 400 {
+		t.Error("400 - Bad Request http status code expected. Got:", response.StatusCode)
+	}
+}
+
+func TestDetectNoMutantRespondsForbidden(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{},
+		Body:    "{\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"]}",
+	}
+	req.Headers["content-type"] = "application/json"
+	d := Handler{
+		notifier: &mockSNSClient{},
+	}
+	response, _ := d.DetectMutant(req)
+	if response.StatusCode != 403 {
+		t.Error("403 - Forbidden http status code expected. Got:", response.StatusCode)
+	}
+}
+
+func TestDetectMutantRespondsOk(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{},
+		Body:    "{\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATGT\",\"AGAAGG\",\"CCCCTA\",\"TCACTG\"]}",
+	}
+	req.Headers["content-type"] = "application/json"
+	d := Handler{
+		notifier: &mockSNSClient{},
+	}
+	response, _ := d.DetectMutant(req)
+	if response.StatusCode != 200 {
+		t.Error("200 - Ok http status code expected. Got:", response.StatusCode)
+	}
+	if response.Headers[DNA_UUID_HEADER] == "" {
+		t.Error("Expected the uuid of the dna. Got:", response.Headers)
+	}
+}
+
+type mockSNSClientPublished struct {
+	snsiface.SNSAPI
+	input *sns.PublishInput
+}
+
+func (m *mockSNSClientPublished) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	m.input = input
+	return &sns.PublishOutput{}, nil
+}
+
+func TestDetectMutantPublishesCorrelationId(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{},
+		Body:    "{\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATGT\",\"AGAAGG\",\"CCCCTA\",\"TCACTG\"]}",
+	}
+	req.Headers["content-type"] = "application/json"
+	req.Headers["x-correlation-id"] = "abc"
+	notifier := &mockSNSClientPublished{}
+	d := Handler{
+		notifier: notifier,
+	}
+	response, _ := d.DetectMutant(req)
+	if response.Headers[logging.CORRELATION_ID_HEADER] != "abc" {
+		t.Error("Expected the correlation id of the request. Got:", response.Headers)
+	}
+	attribute := notifier.input.MessageAttributes[logging.CORRELATION_ID]
+	if attribute == nil || *attribute.StringValue != "abc" {
+		t.Error("Expected the correlation id in the attributes. Got:", notifier.input.MessageAttributes)
+	}
+	message := Message{}
+	json.Unmarshal([]byte(*notifier.input.Message), &message)
+	if message.CorrelationId != "abc" || message.Uuid != response.Headers[DNA_UUID_HEADER] {
+		t.Error("Expected the correlation id and the dna in the message. Got:", *notifier.input.Message)
+	}
+}
+
+func TestDetectMutantGeneratesCorrelationId(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{},
+		Body:    "{\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"]}",
+	}
+	req.Headers["content-type"] = "application/json"
+	d := Handler{
+		notifier: &mockSNSClient{},
+	}
+	response, _ := d.DetectMutant(req)
+	if response.Headers[logging.CORRELATION_ID_HEADER] == "" {
+		t.Error("Expected a new correlation id. Got:", response.Headers)
+	}
+}
+
+func TestDetectMutantPublishesTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{},
+		Body:    "{\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATGT\",\"AGAAGG\",\"CCCCTA\",\"TCACTG\"]}",
+	}
+	req.Headers["content-type"] = "application/json"
+	notifier := &mockSNSClientPublished{}
+	d := Handler{
+		notifier: notifier,
+	}
+	d.DetectMutant(req)
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "detect" || spans[1].Name() != "save-dna publish" {
+		t.Fatal("Expected the spans of the detection and the publishing. Got:", spans)
+	}
+	traceparent := notifier.input.MessageAttributes["traceparent"]
+	publish := spans[1].SpanContext()
+	if traceparent == nil || !strings.Contains(*traceparent.StringValue, publish.SpanID().String()) {
+		t.Error("Expected the publish span in the attributes. Got:", notifier.input.MessageAttributes)
+	}
+	message := Message{}
+	json.Unmarshal([]byte(*notifier.input.Message), &message)
+	if message.SubmittedAt == "" {
+		t.Error("Expected when the dna was submitted. Got:", *notifier.input.Message)
+	}
+}
+
+func TestErrorParsingEmptyRequest(t *testing.T) {
+	body := ""
+	_, err := ParseRequest(body)
+	if err == nil {
+		t.Error("Expected error while parsing an empty request", err)
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	body := "{\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"]}"
+	_, err := ParseRequest(body)
+	if err != nil {
+		t.Error("No error expected while parsing request", err)
+	}
+}
+
+func TestInvalidDnaParsingRequest(t *testing.T) {
+	body := "{\"dna\":[\"XTGAGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"]}"
+	_, err := ParseRequest(body)
+	if err == nil {
+		t.Error("Expected error while parsing request with an invalid dna", err)
+	}
+}
+
+func TestDetectMutantNotSquareDna(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"content-type": "application/json"},
+		Body:    "{\"dna\":[\"AAAA\",\"C\",\"AAAA\",\"AAAA\"]}",
+	}
+	notifier := &mockSNSClientPublished{}
+	d := Handler{
+		notifier: notifier,
+	}
+	response, _ := d.DetectMutant(req)
+	if response.StatusCode != 400 {
+		t.Error("400 - Bad Request http status code expected. Got:", response.StatusCode)
+	}
+	if notifier.input != nil {
+		t.Error("Expected the dna not to be published")
+	}
+}
+
+func TestRespondOK(t *testing.T) {
+	response, err := Respond(200)
+	if response.Body != "OK" || err != nil {
+		t.Error("OK response expected")
+	}
+}
@@ -0,0 +1,93 @@
+package mutant
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/middleware"
+	"github.com/fpinatares/magneto/router"
+	"github.com/fpinatares/magneto/server"
+	"github.com/fpinatares/magneto/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const DEFAULT_MUTANT_ADDRESS = ":8080"
+const NOTIFY_TIMEOUT = 10 * time.Second
+
+// HTTPNotifier posts the messages to the save server instead of publishing
+// them to the topic
+type HTTPNotifier struct {
+	snsiface.SNSAPI
+	URL    string
+	Client *http.Client
+}
+
+func NewHTTPNotifier(url string) *HTTPNotifier {
+	return &HTTPNotifier{
+		URL: url,
+		Client: &http.Client{
+			Timeout: NOTIFY_TIMEOUT,
+		},
+	}
+}
+
+func (n *HTTPNotifier) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	if input.Message == nil {
+		return nil, errors.New("missing message")
+	}
+	messageId := uuid.New().String()
+	req, err := http.NewRequest(http.MethodPost, n.URL, strings.NewReader(*input.Message))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Message-Id", messageId)
+	if attribute, ok := input.MessageAttributes[logging.CORRELATION_ID]; ok && attribute.StringValue != nil {
+		req.Header.Set(logging.CORRELATION_ID_HEADER, *attribute.StringValue)
+	}
+	ctx := tracing.Propagator().Extract(context.Background(), tracing.MessageAttributes(input.MessageAttributes))
+	tracing.Propagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	response, err := n.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("save server answered %d", response.StatusCode)
+	}
+	return &sns.PublishOutput{
+		MessageId: &messageId,
+	}, nil
+}
+
+// RunServe serves the detector. With -save-url the dnas are posted to the
+// save server, otherwise they are published to the topic
+func (d *Handler) RunServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	address := server.AddressFlag(flags, DEFAULT_MUTANT_ADDRESS)
+	saveURL := flags.String("save-url", "", "url of the save server, like http://localhost:8082/save")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *saveURL != "" {
+		d.notifier = NewHTTPNotifier(*saveURL)
+	}
+	return server.Serve(*address, d.Routes())
+}
+
+func (d *Handler) Routes() http.Handler {
+	r := router.New([]server.Route{
+		{Method: http.MethodPost, Path: "/mutant", Handler: d.DetectMutant},
+	})
+	return server.Adapt(middleware.Default(r.Handle))
+}
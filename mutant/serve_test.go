@@ -0,0 +1,48 @@
+package mutant
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+func TestHTTPNotifier(t *testing.T) {
+	var body, messageId string
+	save := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bytes, _ := ioutil.ReadAll(r.Body)
+		body = string(bytes)
+		messageId = r.Header.Get("X-Message-Id")
+	}))
+	defer save.Close()
+	d := Handler{
+		notifier: NewHTTPNotifier(save.URL),
+	}
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    "{\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATGT\",\"AGAAGG\",\"CCCCTA\",\"TCACTG\"]}",
+	}
+	response, _ := d.DetectMutant(req)
+	if response.StatusCode != 200 {
+		t.Error("200 - Ok http status code expected. Got:", response.StatusCode)
+	}
+	if !strings.Contains(body, "\"type\":\"Mutant\"") || messageId == "" {
+		t.Error("Expected the dna to be posted to the save server. Got:", body)
+	}
+}
+
+func TestHTTPNotifierError(t *testing.T) {
+	save := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer save.Close()
+	message := "{}"
+	_, err := NewHTTPNotifier(save.URL).Publish(&sns.PublishInput{Message: &message})
+	if err == nil {
+		t.Error("Expected error when the save server fails")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/repository"
+)
+
+const DEFAULT_SEGMENTS = 4
+
+// LAMBDA_RUNTIME_API is only set inside the Lambda runtime, so without it the
+// binary runs the reconciliation from a terminal
+const LAMBDA_RUNTIME_API = "AWS_LAMBDA_RUNTIME_API"
+
+// ReconcileRequest only reports the differences unless Apply is set, so an
+// empty event can not overwrite the counters
+type ReconcileRequest struct {
+	Apply    bool `json:"apply"`
+	Segments int  `json:"segments"`
+}
+
+type Difference struct {
+	DnaType string `json:"dna_type"`
+	Stored  int    `json:"stored"`
+	Actual  int    `json:"actual"`
+}
+
+type Report struct {
+	Applied     bool         `json:"applied"`
+	Differences []Difference `json:"differences"`
+	Updated     []string     `json:"updated"`
+}
+
+type dependencies struct {
+	repo *repository.DynamoDBRepository
+}
+
+func main() {
+	logging.Setup()
+	svc := GetDynamoDBClient()
+	repo, err := repository.New(repository.ConfigFromEnv(), svc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dynamo, ok := repo.(*repository.DynamoDBRepository)
+	if !ok {
+		log.Fatal("The stats can only be reconciled in the dynamodb repository")
+	}
+	d := dependencies{
+		repo: dynamo,
+	}
+	if os.Getenv(LAMBDA_RUNTIME_API) == "" {
+		err := d.RunReconcile(os.Args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	lambda.Start(func(ctx context.Context, request ReconcileRequest) (Report, error) {
+		report, err := d.Reconcile(request)
+		logging.StartInvocation(ctx).Info("Reconciled stats", "applied", report.Applied, "differences", len(report.Differences), "updated", len(report.Updated))
+		return report, err
+	})
+}
+
+func (d *dependencies) RunReconcile(args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	apply := flags.Bool("apply", false, "overwrite the stats counters that drifted instead of only reporting them")
+	segments := flags.Int("segments", DEFAULT_SEGMENTS, "number of parallel scan segments of the dnas table")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	report, err := d.Reconcile(ReconcileRequest{
+		Apply:    *apply,
+		Segments: *segments,
+	})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// Reconcile recomputes the count of each type from the dnas table and, when
+// the request applies it, overwrites the stats counters that drifted. Saves
+// that happen while it runs can make the new values drift again
+func (d *dependencies) Reconcile(request ReconcileRequest) (Report, error) {
+	report := Report{
+		Applied:     request.Apply,
+		Differences: []Difference{},
+		Updated:     []string{},
+	}
+	segments := request.Segments
+	if segments < 1 {
+		segments = DEFAULT_SEGMENTS
+	}
+	actual, err := d.repo.CountDnas(segments)
+	if err != nil {
+		return report, err
+	}
+	items, err := d.repo.GetStatItems()
+	if err != nil {
+		return report, err
+	}
+	stored := map[string]int{}
+	shards := map[string][]string{}
+	for key, count := range items {
+		dnaType := repository.ShardType(key)
+		stored[dnaType] += count
+		shards[dnaType] = append(shards[dnaType], key)
+	}
+	report.Differences = GetDifferences(stored, actual)
+	if !request.Apply {
+		return report, nil
+	}
+	for _, difference := range report.Differences {
+		err = d.repo.ResetStat(difference.DnaType, difference.Actual, shards[difference.DnaType])
+		if err != nil {
+			return report, err
+		}
+		report.Updated = append(report.Updated, difference.DnaType)
+	}
+	return report, nil
+}
+
+func GetDifferences(stored map[string]int, actual map[string]int) []Difference {
+	types := map[string]bool{}
+	for dnaType := range stored {
+		types[dnaType] = true
+	}
+	for dnaType := range actual {
+		types[dnaType] = true
+	}
+	differences := []Difference{}
+	for dnaType := range types {
+		if stored[dnaType] != actual[dnaType] {
+			differences = append(differences, Difference{
+				DnaType: dnaType,
+				Stored:  stored[dnaType],
+				Actual:  actual[dnaType],
+			})
+		}
+	}
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].DnaType < differences[j].DnaType
+	})
+	return differences
+}
+
+func GetDynamoDBClient() *dynamodb.DynamoDB {
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	svc := dynamodb.New(sess)
+	return svc
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fpinatares/magneto/repository"
+)
+
+type mockDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	mu           sync.Mutex
+	segments     []int64
+	transactions []*dynamodb.TransactWriteItemsInput
+}
+
+type mockDynamoDBClientError struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m *mockDynamoDBClient) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	if *input.TableName == repository.STATS_TABLE {
+		fn(&dynamodb.ScanOutput{
+			Items: []map[string]*dynamodb.AttributeValue{
+				{"dna_type": {S: aws.String("Human")}, "type_count": {N: aws.String("1")}},
+				{"dna_type": {S: aws.String("Human#1")}, "type_count": {N: aws.String("1")}},
+				{"dna_type": {S: aws.String("Mutant")}, "type_count": {N: aws.String("1")}},
+			},
+		}, true)
+		return nil
+	}
+	m.mu.Lock()
+	m.segments = append(m.segments, *input.Segment)
+	m.mu.Unlock()
+	fn(&dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{
+			{"type": {S: aws.String("Human")}},
+		},
+	}, false)
+	fn(&dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{
+			{"type": {S: aws.String("Mutant")}},
+		},
+	}, true)
+	return nil
+}
+
+func (m *mockDynamoDBClient) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.transactions = append(m.transactions, input)
+	return nil, nil
+}
+
+func (m *mockDynamoDBClientError) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	return errors.New("Scan error")
+}
+
+func TestReconcileOnlyReportsByDefault(t *testing.T) {
+	db := &mockDynamoDBClient{}
+	d := dependencies{
+		repo: repository.NewDynamoDBRepository(db),
+	}
+	report, err := d.Reconcile(ReconcileRequest{Segments: 2})
+	if err != nil {
+		t.Error("No error expected reconciling", err)
+	}
+	if len(db.segments) != 2 {
+		t.Error("Expected 2 scanned segments. Got:", db.segments)
+	}
+	if len(report.Differences) != 1 || report.Differences[0].DnaType != "Mutant" || report.Differences[0].Stored != 1 || report.Differences[0].Actual != 2 {
+		t.Error("Expected only the mutant count to differ. Got:", report.Differences)
+	}
+	if len(db.transactions) != 0 || len(report.Updated) != 0 || report.Applied {
+		t.Error("No writes expected without apply")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	db := &mockDynamoDBClient{}
+	d := dependencies{
+		repo: repository.NewDynamoDBRepository(db),
+	}
+	report, err := d.Reconcile(ReconcileRequest{Apply: true, Segments: 3})
+	if err != nil {
+		t.Error("No error expected reconciling", err)
+	}
+	if len(report.Differences) != 2 {
+		t.Fatal("Expected human and mutant counts to differ. Got:", report.Differences)
+	}
+	if len(db.transactions) != 2 || len(report.Updated) != 2 {
+		t.Fatal("Expected both counters to be overwritten. Got:", report.Updated)
+	}
+	human := db.transactions[0].TransactItems
+	if len(human) != 2 || *human[0].Put.Item["type_count"].N != "3" || *human[1].Put.Item["type_count"].N != "0" {
+		t.Error("Expected the human counter to be 3 with its other shard set to 0")
+	}
+}
+
+func TestResetStatWithManyShards(t *testing.T) {
+	db := &mockDynamoDBClient{}
+	r := repository.NewDynamoDBRepository(db)
+	err := r.ResetStat("Mutant", 5, repository.ShardKeys("Mutant", 150))
+	if err != nil {
+		t.Error("No error expected resetting the stat", err)
+	}
+	if len(db.transactions) != 2 || len(db.transactions[0].TransactItems) != 100 || len(db.transactions[1].TransactItems) != 50 {
+		t.Fatal("Expected the shards to be reset in transactions of up to 100 items. Got:", len(db.transactions))
+	}
+	if *db.transactions[0].TransactItems[0].Put.Item["type_count"].N != "5" || *db.transactions[1].TransactItems[49].Put.Item["type_count"].N != "0" {
+		t.Error("Expected the count in the first shard and zero in the rest")
+	}
+}
+
+func TestErrorOnReconcile(t *testing.T) {
+	d := dependencies{
+		repo: repository.NewDynamoDBRepository(&mockDynamoDBClientError{}),
+	}
+	_, err := d.Reconcile(ReconcileRequest{})
+	if err == nil {
+		t.Error("Expected error reconciling")
+	}
+}
+
+func TestGetDifferences(t *testing.T) {
+	stored := map[string]int{"Human": 2, "Mutant": 1}
+	actual := map[string]int{"Human": 2, "Cyborg": 1}
+	differences := GetDifferences(stored, actual)
+	if len(differences) != 2 || differences[0].DnaType != "Cyborg" || differences[1].DnaType != "Mutant" {
+		t.Error("Expected the cyborg and mutant counts to differ. Got:", differences)
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+const HOUR = "hour"
+const DAY = "day"
+const MAX_BUCKETS = 1000
+
+var Granularities = []string{HOUR, DAY}
+
+// Bucket holds the count per dna type of the dnas saved between Start and the
+// start of the next bucket
+type Bucket struct {
+	Start  time.Time
+	Counts map[string]int
+}
+
+// BucketRepository is implemented by the repositories that keep, besides the
+// totals, a counter per type for every hour and day
+type BucketRepository interface {
+	GetBuckets(granularity string, from time.Time, to time.Time) ([]Bucket, error)
+}
+
+func BucketStart(granularity string, t time.Time) time.Time {
+	t = t.UTC()
+	if granularity == HOUR {
+		return t.Truncate(time.Hour)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func NextBucket(granularity string, start time.Time) time.Time {
+	if granularity == HOUR {
+		return start.Add(time.Hour)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// BucketKey identifies the bucket of the given granularity that contains t.
+// The keys of the same granularity sort in time order
+func BucketKey(granularity string, t time.Time) string {
+	start := BucketStart(granularity, t)
+	if granularity == HOUR {
+		return HOUR + SHARD_SEPARATOR + start.Format("2006-01-02T15")
+	}
+	return DAY + SHARD_SEPARATOR + start.Format("2006-01-02")
+}
+
+// BucketKeys returns the key of the hour and the day that contain t
+func BucketKeys(t time.Time) []string {
+	keys := []string{}
+	for _, granularity := range Granularities {
+		keys = append(keys, BucketKey(granularity, t))
+	}
+	return keys
+}
+
+// BucketStarts lists the start of every bucket between from and to, both
+// included
+func BucketStarts(granularity string, from time.Time, to time.Time) ([]time.Time, error) {
+	if granularity != HOUR && granularity != DAY {
+		return nil, fmt.Errorf("granularity %s not supported", granularity)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+	starts := []time.Time{}
+	for start := BucketStart(granularity, from); !start.After(to); start = NextBucket(granularity, start) {
+		if len(starts) == MAX_BUCKETS {
+			return nil, fmt.Errorf("the range can not have more than %d buckets", MAX_BUCKETS)
+		}
+		starts = append(starts, start)
+	}
+	return starts, nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketKey(t *testing.T) {
+	now := time.Date(2026, 10, 19, 5, 30, 0, 0, time.UTC)
+	if BucketKey(HOUR, now) != "hour#2026-10-19T05" {
+		t.Error("Unexpected hour bucket key:", BucketKey(HOUR, now))
+	}
+	if BucketKey(DAY, now) != "day#2026-10-19" {
+		t.Error("Unexpected day bucket key:", BucketKey(DAY, now))
+	}
+}
+
+func TestBucketStarts(t *testing.T) {
+	from := time.Date(2026, 10, 19, 5, 30, 0, 0, time.UTC)
+	starts, err := BucketStarts(DAY, from, from.AddDate(0, 0, 2))
+	if err != nil {
+		t.Error("No error expected listing buckets", err)
+	}
+	if len(starts) != 3 || !starts[0].Equal(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected 3 days starting at midnight. Got:", starts)
+	}
+}
+
+func TestBucketStartsWithInvalidRange(t *testing.T) {
+	from := time.Date(2026, 10, 19, 5, 30, 0, 0, time.UTC)
+	_, err := BucketStarts(HOUR, from, from.Add(-time.Hour))
+	if err == nil {
+		t.Error("Expected error when from is after to")
+	}
+	_, err = BucketStarts("minute", from, from)
+	if err == nil {
+		t.Error("Expected error with an unsupported granularity")
+	}
+	_, err = BucketStarts(HOUR, from, from.AddDate(1, 0, 0))
+	if err == nil {
+		t.Error("Expected error with too many buckets")
+	}
+}
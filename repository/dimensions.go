@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+)
+
+const (
+	SIZE      = "size"
+	SEQUENCES = "sequences"
+	BASE      = "base"
+	DIRECTION = "direction"
+	// RUN_LENGTH is the number of equal bases that make a sequence
+	RUN_LENGTH = 4
+	NONE       = "none"
+)
+
+const (
+	HORIZONTAL = "horizontal"
+	VERTICAL   = "vertical"
+	DIAGONAL   = "diagonal"
+)
+
+var Dimensions = []string{SIZE, SEQUENCES, BASE, DIRECTION}
+
+// DimensionValues are every value a dimension can take, so the groups can be
+// read by key
+var DimensionValues = map[string][]string{
+	SIZE:      {"0-3", "4-6", "7-10", "11-20", "21+"},
+	SEQUENCES: {"0", "1", "2", "3", "4+"},
+	BASE:      {"A", "C", "G", "T", NONE},
+	DIRECTION: {HORIZONTAL, VERTICAL, DIAGONAL, NONE},
+}
+
+// Group holds the count per dna type of the dnas with a value of a dimension
+type Group struct {
+	Value  string
+	Counts map[string]int
+}
+
+// DimensionRepository is implemented by the repositories that count the dnas
+// by their dimensions when they are recorded
+type DimensionRepository interface {
+	GetGroups(dimension string) ([]Group, error)
+}
+
+var ErrUnknownDimension = errors.New("unknown dimension")
+
+func IsDimension(dimension string) bool {
+	_, ok := DimensionValues[dimension]
+	return ok
+}
+
+// NewGroups returns an empty group for every value of the dimension
+func NewGroups(dimension string) ([]Group, error) {
+	values, ok := DimensionValues[dimension]
+	if !ok {
+		return nil, ErrUnknownDimension
+	}
+	groups := []Group{}
+	for _, value := range values {
+		groups = append(groups, Group{
+			Value:  value,
+			Counts: map[string]int{},
+		})
+	}
+	return groups, nil
+}
+
+func DimensionKey(dimension string, value string) string {
+	return dimension + "=" + value
+}
+
+// DimensionKeys are the keys of the groups the dna belongs to, one per
+// dimension
+func DimensionKeys(dna []string) []string {
+	values := AnalyzeDna(dna)
+	keys := []string{}
+	for _, dimension := range Dimensions {
+		keys = append(keys, DimensionKey(dimension, values[dimension]))
+	}
+	return keys
+}
+
+// AnalyzeDna finds the value of every dimension of the dna: the bucket of its
+// size, how many sequences it has, and the base and direction most of them
+// have. Unlike the detector it does not stop at the second sequence
+func AnalyzeDna(dna []string) map[string]string {
+	bases := map[string]int{}
+	directions := map[string]int{}
+	sequences := 0
+	steps := map[string][2]int{
+		HORIZONTAL: {0, 1},
+		VERTICAL:   {1, 0},
+		DIAGONAL:   {1, 1},
+	}
+	for i := range dna {
+		for j := range dna[i] {
+			for direction, step := range steps {
+				if IsRun(dna, i, j, step[0], step[1]) {
+					sequences++
+					bases[string(dna[i][j])]++
+					directions[direction]++
+				}
+			}
+		}
+	}
+	return map[string]string{
+		SIZE:      SizeBucket(len(dna)),
+		SEQUENCES: SequencesBucket(sequences),
+		BASE:      Dominant(bases, DimensionValues[BASE]),
+		DIRECTION: Dominant(directions, DimensionValues[DIRECTION]),
+	}
+}
+
+func IsRun(dna []string, i int, j int, di int, dj int) bool {
+	for k := 1; k < RUN_LENGTH; k++ {
+		row := i + k*di
+		column := j + k*dj
+		if row >= len(dna) || column >= len(dna[row]) || dna[row][column] != dna[i][j] {
+			return false
+		}
+	}
+	return true
+}
+
+func SizeBucket(size int) string {
+	switch {
+	case size < 4:
+		return "0-3"
+	case size <= 6:
+		return "4-6"
+	case size <= 10:
+		return "7-10"
+	case size <= 20:
+		return "11-20"
+	}
+	return "21+"
+}
+
+func SequencesBucket(sequences int) string {
+	values := DimensionValues[SEQUENCES]
+	if sequences >= len(values)-1 {
+		return values[len(values)-1]
+	}
+	return values[sequences]
+}
+
+// Dominant is the value with the highest count, breaking ties by the order of
+// the values, or none when every count is zero
+func Dominant(counts map[string]int, values []string) string {
+	ordered := append([]string{}, values...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return counts[ordered[i]] > counts[ordered[j]]
+	})
+	if counts[ordered[0]] == 0 {
+		return NONE
+	}
+	return ordered[0]
+}
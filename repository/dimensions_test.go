@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"testing"
+)
+
+func TestAnalyzeDna(t *testing.T) {
+	dna := []string{"ATGCGA", "CAGTGC", "TTATGT", "AGAAGG", "CCCCTA", "TCACTG"}
+	values := AnalyzeDna(dna)
+	if values[SIZE] != "4-6" || values[SEQUENCES] != "3" || values[BASE] != "A" || values[DIRECTION] != HORIZONTAL {
+		t.Error("Unexpected dimensions:", values)
+	}
+}
+
+func TestAnalyzeDnaWithoutSequences(t *testing.T) {
+	values := AnalyzeDna([]string{"ATGCGA", "CAGTGC", "TTATTT", "AGACGG", "GCGTCA", "TCACTG"})
+	if values[SEQUENCES] != "0" || values[BASE] != NONE || values[DIRECTION] != NONE {
+		t.Error("Expected no sequences. Got:", values)
+	}
+}
+
+func TestAnalyzeDnaWithManySequences(t *testing.T) {
+	values := AnalyzeDna([]string{"GGGGGG", "GGGGGG", "GGGGGG", "GGGGGG"})
+	if values[SEQUENCES] != "4+" || values[BASE] != "G" || values[DIRECTION] != HORIZONTAL {
+		t.Error("Expected many horizontal runs of G. Got:", values)
+	}
+}
+
+func TestSizeBucket(t *testing.T) {
+	if SizeBucket(3) != "0-3" || SizeBucket(6) != "4-6" || SizeBucket(10) != "7-10" || SizeBucket(20) != "11-20" || SizeBucket(100) != "21+" {
+		t.Error("Unexpected size buckets")
+	}
+}
+
+func TestDimensionKeys(t *testing.T) {
+	keys := DimensionKeys([]string{"AAAA", "CAGT", "TTAT", "AGAA"})
+	if len(keys) != 4 || keys[0] != "size=4-6" || keys[2] != "base=A" {
+		t.Error("Unexpected dimension keys:", keys)
+	}
+}
@@ -0,0 +1,657 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fpinatares/magneto/tracing"
+)
+
+const STATS_TABLE = "stats"
+const DNAS_TABLE = "dnas"
+const STATS_BUCKETS_TABLE = "stats_buckets"
+const STATS_DIMENSIONS_TABLE = "stats_dimensions"
+const BATCH_GET_LIMIT = 100
+const BATCH_GET_BACKOFF = 50 * time.Millisecond
+const BATCH_GET_MAX_BACKOFF = 2 * time.Second
+const BATCH_GET_RETRIES = 8
+const SHARD_SEPARATOR = "#"
+
+// SHARDS_KEY is the item of the stats table that keeps the highest number of
+// shards the counters were written with
+const SHARDS_KEY = SHARD_SEPARATOR + "shards"
+
+// TYPES_KEY is the item of the stats table that keeps every type the writers
+// accept, so the readers also find the counters of the types they do not know
+const TYPES_KEY = SHARD_SEPARATOR + "types"
+
+var ErrUnprocessedKeys = errors.New("keys still unprocessed after every retry")
+
+type DynamoDBRepository struct {
+	db           dynamodbiface.DynamoDBAPI
+	StatsTable   string
+	DnasTable    string
+	BucketsTable string
+	// DimensionsTable keeps the counters per group of each dimension
+	DimensionsTable string
+	// EventsTable keeps the ids of the stream events already applied
+	EventsTable string
+	// ProcessedTable keeps the ids of the messages already saved, and
+	// QuarantineTable the ones that could not be saved
+	ProcessedTable  string
+	QuarantineTable string
+	// StatShards is the number of items each type counter is spread across,
+	// so the writes do not concentrate on a single partition
+	StatShards int
+	// DnaTypes are the types whose counters GetStats reads, besides the ones
+	// recorded by the writers
+	DnaTypes       []string
+	ConsistentRead bool
+	Now            func() time.Time
+	Sleep          func(time.Duration)
+}
+
+func NewDynamoDBRepository(db dynamodbiface.DynamoDBAPI) *DynamoDBRepository {
+	return &DynamoDBRepository{
+		db:              db,
+		StatsTable:      STATS_TABLE,
+		DnasTable:       DNAS_TABLE,
+		BucketsTable:    STATS_BUCKETS_TABLE,
+		DimensionsTable: STATS_DIMENSIONS_TABLE,
+		EventsTable:     PROCESSED_EVENTS_TABLE,
+		ProcessedTable:  PROCESSED_MESSAGES_TABLE,
+		QuarantineTable: QUARANTINE_TABLE,
+		StatShards:      1,
+		DnaTypes:        []string{"Human", "Mutant"},
+		Now:             time.Now,
+		Sleep:           time.Sleep,
+	}
+}
+
+func (r *DynamoDBRepository) WithContext(ctx context.Context) *DynamoDBRepository {
+	repository := *r
+	repository.db = tracing.WithContext(r.db, ctx)
+	return &repository
+}
+
+func (r *DynamoDBRepository) SaveDna(dna DnaData) error {
+	av, err := dynamodbattribute.MarshalMap(dna)
+	if err != nil {
+		log.Printf("Got error calling MarshalMap: %s", err)
+		return err
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(r.DnasTable),
+	}
+	_, err = r.db.PutItem(input)
+	if err != nil {
+		log.Printf("Got error calling PutItem: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (r *DynamoDBRepository) IncrementStat(dnaType string) error {
+	inputs := []*dynamodb.UpdateItemInput{r.CreateUpdateItemInput(dnaType)}
+	inputs = append(inputs, r.CreateBucketUpdateItemInputs(dnaType)...)
+	for _, input := range inputs {
+		_, err := r.db.UpdateItem(input)
+		if err != nil {
+			log.Printf("Got error calling UpdateItem: %s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *DynamoDBRepository) CreateUpdateItemInput(dnaType string) *dynamodb.UpdateItemInput {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.StatsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"dna_type": {
+				S: aws.String(ShardKey(dnaType, r.StatShards)),
+			},
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":inc": {
+				N: aws.String("1"),
+			},
+		},
+		UpdateExpression: aws.String("ADD type_count :inc"),
+	}
+	return input
+}
+
+// CreateBucketUpdateItemInputs increments the counter of the type in the
+// hour and day buckets of the current time. Each type is an attribute of the
+// bucket item
+func (r *DynamoDBRepository) CreateBucketUpdateItemInputs(dnaType string) []*dynamodb.UpdateItemInput {
+	return r.CreateCounterUpdateItemInputs(r.BucketsTable, "bucket", BucketKeys(r.Now()), dnaType)
+}
+
+func (r *DynamoDBRepository) CreateDimensionUpdateItemInputs(dna DnaData) []*dynamodb.UpdateItemInput {
+	return r.CreateCounterUpdateItemInputs(r.DimensionsTable, "dimension", DimensionKeys(dna.Dna), dna.Type)
+}
+
+// CreateCounterUpdateItemInputs increments the attribute of the type in the
+// items with the given keys, which hold a counter per type
+func (r *DynamoDBRepository) CreateCounterUpdateItemInputs(table string, attribute string, keys []string, dnaType string) []*dynamodb.UpdateItemInput {
+	inputs := []*dynamodb.UpdateItemInput{}
+	for _, key := range keys {
+		inputs = append(inputs, &dynamodb.UpdateItemInput{
+			TableName: aws.String(table),
+			Key: map[string]*dynamodb.AttributeValue{
+				attribute: {
+					S: aws.String(ShardKey(key, r.StatShards)),
+				},
+			},
+			ExpressionAttributeNames: map[string]*string{
+				"#type": aws.String(dnaType),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":inc": {
+					N: aws.String("1"),
+				},
+			},
+			UpdateExpression: aws.String("ADD #type :inc"),
+		})
+	}
+	return inputs
+}
+
+// RecordDna saves the dna and increments its type counter in a single
+// transaction. The put is conditioned on the uuid not existing, which makes a
+// redelivered dna cancel the whole transaction instead of counting it twice
+func (r *DynamoDBRepository) RecordDna(dna DnaData) error {
+	input, err := r.CreateTransactWriteItemsInput(dna)
+	if err != nil {
+		return err
+	}
+	return r.transactRecord(input)
+}
+
+func (r *DynamoDBRepository) transactRecord(input *dynamodb.TransactWriteItemsInput) error {
+	_, err := r.db.TransactWriteItems(input)
+	if err != nil {
+		if IsAlreadyProcessed(err) {
+			return ErrAlreadyProcessed
+		}
+		if IsDuplicateDna(err) {
+			return ErrDuplicateDna
+		}
+		log.Printf("Got error calling TransactWriteItems: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (r *DynamoDBRepository) CreateTransactWriteItemsInput(dna DnaData) (*dynamodb.TransactWriteItemsInput, error) {
+	av, err := dynamodbattribute.MarshalMap(dna)
+	if err != nil {
+		log.Printf("Got error calling MarshalMap: %s", err)
+		return nil, err
+	}
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Put: &dynamodb.Put{
+					TableName:           aws.String(r.DnasTable),
+					Item:                av,
+					ConditionExpression: aws.String("attribute_not_exists(#uuid)"),
+					ExpressionAttributeNames: map[string]*string{
+						"#uuid": aws.String("uuid"),
+					},
+				},
+			},
+		},
+	}
+	updates := []*dynamodb.UpdateItemInput{r.CreateUpdateItemInput(dna.Type)}
+	updates = append(updates, r.CreateBucketUpdateItemInputs(dna.Type)...)
+	updates = append(updates, r.CreateDimensionUpdateItemInputs(dna)...)
+	for _, update := range updates {
+		input.TransactItems = append(input.TransactItems, ToTransactWriteItem(update))
+	}
+	return input, nil
+}
+
+func ToTransactWriteItem(update *dynamodb.UpdateItemInput) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:                 update.TableName,
+			Key:                       update.Key,
+			ExpressionAttributeNames:  update.ExpressionAttributeNames,
+			ExpressionAttributeValues: update.ExpressionAttributeValues,
+			UpdateExpression:          update.UpdateExpression,
+		},
+	}
+}
+
+// IsDuplicateDna reports whether the transaction was cancelled because the
+// dna had already been saved
+func IsDuplicateDna(err error) bool {
+	return IsConditionFailed(err, 0)
+}
+
+// IsConditionFailed reports whether the transaction was cancelled by the
+// condition of the item at the index
+func IsConditionFailed(err error, index int) bool {
+	canceled, ok := err.(*dynamodb.TransactionCanceledException)
+	if !ok || len(canceled.CancellationReasons) <= index {
+		return false
+	}
+	return aws.StringValue(canceled.CancellationReasons[index].Code) == "ConditionalCheckFailed"
+}
+
+// GetStats reads by key every shard of DnaTypes and of the types recorded by
+// the writers, up to the highest number of shards recorded by them
+func (r *DynamoDBRepository) GetStats() ([]StatDB, error) {
+	shards, err := r.Shards()
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := r.RecordedTypes()
+	if err != nil {
+		return nil, err
+	}
+	keys := []map[string]*dynamodb.AttributeValue{}
+	read := map[string]bool{}
+	for _, dnaType := range append(append([]string{}, r.DnaTypes...), recorded...) {
+		if read[dnaType] {
+			continue
+		}
+		read[dnaType] = true
+		for _, key := range ShardKeys(dnaType, shards) {
+			keys = append(keys, map[string]*dynamodb.AttributeValue{
+				"dna_type": {
+					S: aws.String(key),
+				},
+			})
+		}
+	}
+	items, err := r.BatchGetAll(r.StatsTable, keys)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, i := range items {
+		stat, err := ParseItem(i)
+		if err != nil {
+			return nil, err
+		}
+		counts[ShardType(stat.DnaType)] += stat.Count
+	}
+	return SortStats(counts), nil
+}
+
+// ShardKey picks at random one of the shards of the type counter. The first
+// shard keeps the plain type as key, so the counters written before sharding
+// was enabled are still used
+func ShardKey(dnaType string, shards int) string {
+	if shards <= 1 {
+		return dnaType
+	}
+	shard := rand.Intn(shards)
+	if shard == 0 {
+		return dnaType
+	}
+	return dnaType + SHARD_SEPARATOR + strconv.Itoa(shard)
+}
+
+// RecordShards keeps StatShards in the stats table unless a higher number was
+// recorded, so the readers still find every shard after STATS_SHARDS is
+// lowered. The writers call it before counting anything
+func (r *DynamoDBRepository) RecordShards() error {
+	if r.StatShards <= 1 {
+		return nil
+	}
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.StatsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"dna_type": {
+				S: aws.String(SHARDS_KEY),
+			},
+		},
+		UpdateExpression:    aws.String("SET shards = :shards"),
+		ConditionExpression: aws.String("attribute_not_exists(shards) OR shards < :shards"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":shards": {
+				N: aws.String(strconv.Itoa(r.StatShards)),
+			},
+		},
+	}
+	_, err := r.db.UpdateItem(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return nil
+	}
+	if err != nil {
+		log.Printf("Got error calling UpdateItem: %s", err)
+		return err
+	}
+	return nil
+}
+
+// Shards is the number of shards to read: the highest number recorded by the
+// writers, or StatShards when it is higher
+func (r *DynamoDBRepository) Shards() (int, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(r.StatsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"dna_type": {
+				S: aws.String(SHARDS_KEY),
+			},
+		},
+		ConsistentRead: aws.Bool(r.ConsistentRead),
+	}
+	result, err := r.db.GetItem(input)
+	if err != nil {
+		log.Printf("Got error calling GetItem: %s", err)
+		return 0, err
+	}
+	shards := r.StatShards
+	if result != nil && result.Item["shards"] != nil {
+		recorded, err := strconv.Atoi(aws.StringValue(result.Item["shards"].N))
+		if err != nil {
+			return 0, err
+		}
+		if recorded > shards {
+			shards = recorded
+		}
+	}
+	return shards, nil
+}
+
+// RecordTypes adds DnaTypes to the types recorded in the stats table. The
+// writers call it before counting anything, like RecordShards
+func (r *DynamoDBRepository) RecordTypes() error {
+	if len(r.DnaTypes) == 0 {
+		return nil
+	}
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.StatsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"dna_type": {
+				S: aws.String(TYPES_KEY),
+			},
+		},
+		UpdateExpression: aws.String("ADD dna_types :types"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":types": {
+				SS: aws.StringSlice(r.DnaTypes),
+			},
+		},
+	}
+	_, err := r.db.UpdateItem(input)
+	if err != nil {
+		log.Printf("Got error calling UpdateItem: %s", err)
+		return err
+	}
+	return nil
+}
+
+// RecordedTypes are the types the writers recorded with RecordTypes
+func (r *DynamoDBRepository) RecordedTypes() ([]string, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(r.StatsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"dna_type": {
+				S: aws.String(TYPES_KEY),
+			},
+		},
+		ConsistentRead: aws.Bool(r.ConsistentRead),
+	}
+	result, err := r.db.GetItem(input)
+	if err != nil {
+		log.Printf("Got error calling GetItem: %s", err)
+		return nil, err
+	}
+	if result == nil || result.Item["dna_types"] == nil {
+		return []string{}, nil
+	}
+	return aws.StringValueSlice(result.Item["dna_types"].SS), nil
+}
+
+// ShardKeys lists the keys of every shard of the counter
+func ShardKeys(key string, shards int) []string {
+	keys := []string{key}
+	for shard := 1; shard < shards; shard++ {
+		keys = append(keys, key+SHARD_SEPARATOR+strconv.Itoa(shard))
+	}
+	return keys
+}
+
+func ShardType(key string) string {
+	return strings.SplitN(key, SHARD_SEPARATOR, 2)[0]
+}
+
+func SortStats(counts map[string]int) []StatDB {
+	stats := []StatDB{}
+	for dnaType, count := range counts {
+		stats = append(stats, StatDB{
+			DnaType: dnaType,
+			Count:   count,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].DnaType < stats[j].DnaType
+	})
+	return stats
+}
+
+func ParseItem(item map[string]*dynamodb.AttributeValue) (StatDB, error) {
+	stat := StatDB{}
+	err := dynamodbattribute.UnmarshalMap(item, &stat)
+	if err != nil {
+		log.Printf("Got error unmarshalling: %s", err)
+	}
+	return stat, err
+}
+
+func (r *DynamoDBRepository) GetDna(uuid string) (DnaData, error) {
+	dna := DnaData{}
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(r.DnasTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"uuid": {
+				S: aws.String(uuid),
+			},
+		},
+	}
+	result, err := r.db.GetItem(input)
+	if err != nil {
+		log.Printf("Got error calling GetItem: %s", err)
+		return dna, err
+	}
+	if result == nil || len(result.Item) == 0 {
+		return dna, ErrNotFound
+	}
+	err = dynamodbattribute.UnmarshalMap(result.Item, &dna)
+	if err != nil {
+		log.Printf("Got error unmarshalling: %s", err)
+	}
+	return dna, err
+}
+
+func (r *DynamoDBRepository) ListDnas() ([]DnaData, error) {
+	dnas := []DnaData{}
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(r.DnasTable),
+	}
+	var uerr error
+	err := r.db.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		items := []DnaData{}
+		uerr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &items)
+		dnas = append(dnas, items...)
+		return uerr == nil
+	})
+	if err == nil {
+		err = uerr
+	}
+	if err != nil {
+		log.Printf("Got error scanning dnas: %s", err)
+		return nil, err
+	}
+	return dnas, nil
+}
+
+// GetBuckets reads the items of every bucket in the range by key, with the
+// shards of GetStats
+func (r *DynamoDBRepository) GetBuckets(granularity string, from time.Time, to time.Time) ([]Bucket, error) {
+	starts, err := BucketStarts(granularity, from, to)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := r.Shards()
+	if err != nil {
+		return nil, err
+	}
+	buckets := []Bucket{}
+	indexes := map[string]int{}
+	keys := []map[string]*dynamodb.AttributeValue{}
+	for i, start := range starts {
+		buckets = append(buckets, Bucket{
+			Start:  start,
+			Counts: map[string]int{},
+		})
+		for _, key := range ShardKeys(BucketKey(granularity, start), shards) {
+			indexes[key] = i
+			keys = append(keys, map[string]*dynamodb.AttributeValue{
+				"bucket": {
+					S: aws.String(key),
+				},
+			})
+		}
+	}
+	items, err := r.BatchGetAll(r.BucketsTable, keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		err = AddCounts(buckets[indexes[aws.StringValue(item["bucket"].S)]].Counts, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buckets, nil
+}
+
+// GetGroups reads every shard of every group of the dimension
+func (r *DynamoDBRepository) GetGroups(dimension string) ([]Group, error) {
+	groups, err := NewGroups(dimension)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := r.Shards()
+	if err != nil {
+		return nil, err
+	}
+	indexes := map[string]int{}
+	keys := []map[string]*dynamodb.AttributeValue{}
+	for i, group := range groups {
+		for _, key := range ShardKeys(DimensionKey(dimension, group.Value), shards) {
+			indexes[key] = i
+			keys = append(keys, map[string]*dynamodb.AttributeValue{
+				"dimension": {
+					S: aws.String(key),
+				},
+			})
+		}
+	}
+	items, err := r.BatchGetAll(r.DimensionsTable, keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		err = AddCounts(groups[indexes[aws.StringValue(item["dimension"].S)]].Counts, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// AddCounts adds every number attribute of the item, which are the counters
+// per type, to the counts
+func AddCounts(counts map[string]int, item map[string]*dynamodb.AttributeValue) error {
+	for name, value := range item {
+		if value.N == nil {
+			continue
+		}
+		count, err := strconv.Atoi(*value.N)
+		if err != nil {
+			return err
+		}
+		counts[name] += count
+	}
+	return nil
+}
+
+// BatchGetAll reads the items with the given keys, in batches of up to 100
+// keys and retrying the keys DynamoDB leaves unprocessed. The wait between
+// retries doubles up to BATCH_GET_MAX_BACKOFF, and ErrUnprocessedKeys is
+// returned when BATCH_GET_RETRIES in a row leave keys unprocessed
+func (r *DynamoDBRepository) BatchGetAll(table string, keys []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+	items := []map[string]*dynamodb.AttributeValue{}
+	retries := 0
+	backoff := BATCH_GET_BACKOFF
+	for len(keys) > 0 {
+		size := BATCH_GET_LIMIT
+		if len(keys) < size {
+			size = len(keys)
+		}
+		batch, unprocessed, err := r.BatchGet(table, keys[:size])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+		if len(unprocessed) == 0 {
+			retries = 0
+			backoff = BATCH_GET_BACKOFF
+		} else {
+			if retries == BATCH_GET_RETRIES {
+				log.Printf("Got %d unprocessed keys of %s after %d retries", len(unprocessed), table, retries)
+				return nil, ErrUnprocessedKeys
+			}
+			r.Sleep(backoff)
+			retries++
+			backoff *= 2
+			if backoff > BATCH_GET_MAX_BACKOFF {
+				backoff = BATCH_GET_MAX_BACKOFF
+			}
+		}
+		keys = append(unprocessed, keys[size:]...)
+	}
+	return items, nil
+}
+
+func (r *DynamoDBRepository) BatchGet(table string, keys []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, []map[string]*dynamodb.AttributeValue, error) {
+	input := &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			table: {
+				Keys:           keys,
+				ConsistentRead: aws.Bool(r.ConsistentRead),
+			},
+		},
+	}
+	result, err := r.db.BatchGetItem(input)
+	if err != nil {
+		log.Printf("Got error calling BatchGetItem: %s", err)
+		return nil, nil, err
+	}
+	unprocessed := []map[string]*dynamodb.AttributeValue{}
+	if pending, ok := result.UnprocessedKeys[table]; ok {
+		unprocessed = pending.Keys
+	}
+	return result.Responses[table], unprocessed, nil
+}
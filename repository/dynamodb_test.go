@@ -0,0 +1,602 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+type mockDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+type mockDynamoDBClientError struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+type mockDynamoDBClientDuplicate struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+type mockDynamoDBClientShards struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+type mockDynamoDBClientPaging struct {
+	dynamodbiface.DynamoDBAPI
+	inputs []*dynamodb.BatchGetItemInput
+}
+
+// GetItem returns the recorded number of shards and a type that only the
+// writers registered
+func (m *mockDynamoDBClientShards) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	switch *input.Key["dna_type"].S {
+	case SHARDS_KEY:
+		return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{"shards": {N: aws.String("8")}}}, nil
+	case TYPES_KEY:
+		return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{"dna_types": {SS: aws.StringSlice([]string{"Cyborg", "Mutant"})}}}, nil
+	}
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (m *mockDynamoDBClientShards) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	if !*input.RequestItems[STATS_TABLE].ConsistentRead {
+		return nil, errors.New("Expected consistent reads of the stats table")
+	}
+	counts := map[string]string{"Human": "2", "Human#1": "1", "Mutant#1": "4", "Mutant#7": "1", "Cyborg#2": "3", "Robot": "9"}
+	items := []map[string]*dynamodb.AttributeValue{}
+	for _, key := range input.RequestItems[STATS_TABLE].Keys {
+		if count, ok := counts[*key["dna_type"].S]; ok {
+			items = append(items, map[string]*dynamodb.AttributeValue{
+				"dna_type":   key["dna_type"],
+				"type_count": {N: aws.String(count)},
+			})
+		}
+	}
+	return &dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]*dynamodb.AttributeValue{
+			STATS_TABLE: items,
+		},
+	}, nil
+}
+
+// BatchGetItem leaves the last key of the first request unprocessed
+func (m *mockDynamoDBClientPaging) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	m.inputs = append(m.inputs, input)
+	keys := input.RequestItems[STATS_TABLE].Keys
+	output := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]*dynamodb.AttributeValue{},
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{},
+	}
+	if len(m.inputs) == 1 {
+		output.UnprocessedKeys[STATS_TABLE] = &dynamodb.KeysAndAttributes{Keys: keys[len(keys)-1:]}
+		keys = keys[:len(keys)-1]
+	}
+	for _, key := range keys {
+		output.Responses[STATS_TABLE] = append(output.Responses[STATS_TABLE], map[string]*dynamodb.AttributeValue{
+			"dna_type":   key["dna_type"],
+			"type_count": {N: aws.String("1")},
+		})
+	}
+	return output, nil
+}
+
+func (m *mockDynamoDBClient) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	return nil, nil
+}
+
+func (m *mockDynamoDBClientError) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	return nil, errors.New("Update item error")
+}
+
+func (m *mockDynamoDBClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return nil, nil
+}
+
+func (m *mockDynamoDBClientError) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return nil, errors.New("Put item error")
+}
+
+func (m *mockDynamoDBClient) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return nil, nil
+}
+
+func (m *mockDynamoDBClientError) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return nil, errors.New("Transact write items error")
+}
+
+func (m *mockDynamoDBClientDuplicate) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return nil, &dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed")},
+			{Code: aws.String("None")},
+		},
+	}
+}
+
+func (m *mockDynamoDBClient) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	if *input.TableName == STATS_TABLE {
+		fn(&dynamodb.ScanOutput{
+			Items: []map[string]*dynamodb.AttributeValue{
+				{
+					"dna_type":   {S: aws.String("Human")},
+					"type_count": {N: aws.String("2")},
+				},
+			},
+		}, true)
+		return nil
+	}
+	fn(&dynamodb.ScanOutput{
+		Items: []map[string]*dynamodb.AttributeValue{
+			{
+				"uuid": {S: aws.String("1")},
+				"dna":  {L: []*dynamodb.AttributeValue{{S: aws.String("ATGC")}}},
+				"type": {S: aws.String("Human")},
+			},
+		},
+	}, true)
+	return nil
+}
+
+func (m *mockDynamoDBClientError) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	return errors.New("Scan error")
+}
+
+func (m *mockDynamoDBClient) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	if input.Key["uuid"] == nil || *input.Key["uuid"].S != "1" {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	return &dynamodb.GetItemOutput{
+		Item: map[string]*dynamodb.AttributeValue{
+			"uuid": {S: aws.String("1")},
+			"dna":  {L: []*dynamodb.AttributeValue{{S: aws.String("ATGC")}}},
+			"type": {S: aws.String("Mutant")},
+		},
+	}, nil
+}
+
+func (m *mockDynamoDBClient) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	if stats, ok := input.RequestItems[STATS_TABLE]; ok {
+		items := []map[string]*dynamodb.AttributeValue{}
+		for _, key := range stats.Keys {
+			if *key["dna_type"].S == "Human" {
+				items = append(items, map[string]*dynamodb.AttributeValue{
+					"dna_type":   key["dna_type"],
+					"type_count": {N: aws.String("2")},
+				})
+			}
+		}
+		return &dynamodb.BatchGetItemOutput{
+			Responses: map[string][]map[string]*dynamodb.AttributeValue{
+				STATS_TABLE: items,
+			},
+		}, nil
+	}
+	if dimensions, ok := input.RequestItems[STATS_DIMENSIONS_TABLE]; ok {
+		items := []map[string]*dynamodb.AttributeValue{}
+		for _, key := range dimensions.Keys {
+			switch *key["dimension"].S {
+			case "size=4-6", "size=4-6#1":
+				items = append(items, map[string]*dynamodb.AttributeValue{
+					"dimension": key["dimension"],
+					"Mutant":    {N: aws.String("1")},
+				})
+			}
+		}
+		return &dynamodb.BatchGetItemOutput{
+			Responses: map[string][]map[string]*dynamodb.AttributeValue{
+				STATS_DIMENSIONS_TABLE: items,
+			},
+		}, nil
+	}
+	items := []map[string]*dynamodb.AttributeValue{}
+	for _, key := range input.RequestItems[STATS_BUCKETS_TABLE].Keys {
+		switch *key["bucket"].S {
+		case "hour#2026-10-19T05":
+			items = append(items, map[string]*dynamodb.AttributeValue{
+				"bucket": key["bucket"],
+				"Mutant": {N: aws.String("2")},
+				"Human":  {N: aws.String("1")},
+			})
+		case "hour#2026-10-19T05#1":
+			items = append(items, map[string]*dynamodb.AttributeValue{
+				"bucket": key["bucket"],
+				"Mutant": {N: aws.String("1")},
+			})
+		}
+	}
+	return &dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]*dynamodb.AttributeValue{
+			STATS_BUCKETS_TABLE: items,
+		},
+	}, nil
+}
+
+func (m *mockDynamoDBClientError) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return nil, errors.New("Get item error")
+}
+
+func (m *mockDynamoDBClientError) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	return nil, errors.New("Batch get item error")
+}
+
+func TestIncrementStat(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	err := r.IncrementStat("Mutant")
+	if err != nil {
+		t.Error("No error expected incrementing stat", err)
+	}
+}
+
+func TestErrorOnIncrementStat(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientError{})
+	err := r.IncrementStat("")
+	if err == nil {
+		t.Error("Expected error while incrementing stat")
+	}
+}
+
+func TestSaveDna(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	err := r.SaveDna(DnaData{Uuid: "1", Type: "Mutant"})
+	if err != nil {
+		t.Error("No error expected saving dna", err)
+	}
+}
+
+func TestErrorOnSaveDna(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientError{})
+	err := r.SaveDna(DnaData{Uuid: "1", Type: "Mutant"})
+	if err == nil {
+		t.Error("Expected error while saving dna")
+	}
+}
+
+func TestRecordDna(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	err := r.RecordDna(DnaData{Uuid: "1", Type: "Mutant"})
+	if err != nil {
+		t.Error("No error expected recording dna", err)
+	}
+}
+
+func TestRecordDuplicateDna(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientDuplicate{})
+	err := r.RecordDna(DnaData{Uuid: "1", Type: "Mutant"})
+	if err != ErrDuplicateDna {
+		t.Error("Expected duplicate dna error. Got:", err)
+	}
+}
+
+func TestErrorOnRecordDna(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientError{})
+	err := r.RecordDna(DnaData{Uuid: "1", Type: "Mutant"})
+	if err == nil || err == ErrDuplicateDna {
+		t.Error("Expected error while recording dna. Got:", err)
+	}
+}
+
+func TestCreateTransactWriteItemsInput(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	input, err := r.CreateTransactWriteItemsInput(DnaData{Uuid: "1", Type: "Mutant"})
+	if err != nil {
+		t.Error("No error expected creating the transaction", err)
+	}
+	if len(input.TransactItems) != 8 {
+		t.Fatal("Expected 8 transact items. Got:", len(input.TransactItems))
+	}
+	put := input.TransactItems[0].Put
+	if put == nil || *put.TableName != DNAS_TABLE || put.ConditionExpression == nil {
+		t.Error("Expected a conditional put on the dnas table")
+	}
+	update := input.TransactItems[1].Update
+	if update == nil || *update.TableName != STATS_TABLE || *update.Key["dna_type"].S != "Mutant" {
+		t.Error("Expected an update of the mutant counter on the stats table")
+	}
+	for _, item := range input.TransactItems[2:4] {
+		if item.Update == nil || *item.Update.TableName != STATS_BUCKETS_TABLE || *item.Update.ExpressionAttributeNames["#type"] != "Mutant" {
+			t.Error("Expected an update of the mutant counter on the buckets table")
+		}
+	}
+	for _, item := range input.TransactItems[4:] {
+		if item.Update == nil || *item.Update.TableName != STATS_DIMENSIONS_TABLE || *item.Update.ExpressionAttributeNames["#type"] != "Mutant" {
+			t.Error("Expected an update of the mutant counter on the dimensions table")
+		}
+	}
+}
+
+func TestGetBuckets(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	r.StatShards = 2
+	from := time.Date(2026, 10, 19, 5, 30, 0, 0, time.UTC)
+	buckets, err := r.GetBuckets(HOUR, from, from.Add(time.Hour))
+	if err != nil {
+		t.Error("No error expected getting buckets", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatal("Expected 2 buckets. Got:", len(buckets))
+	}
+	if buckets[0].Counts["Mutant"] != 3 || buckets[0].Counts["Human"] != 1 {
+		t.Error("Expected the shards of the first bucket to be summed. Got:", buckets[0].Counts)
+	}
+	if len(buckets[1].Counts) != 0 {
+		t.Error("Expected the second bucket to be empty. Got:", buckets[1].Counts)
+	}
+}
+
+func TestErrorOnGetBuckets(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientError{})
+	from := time.Date(2026, 10, 19, 5, 30, 0, 0, time.UTC)
+	_, err := r.GetBuckets(DAY, from, from)
+	if err == nil {
+		t.Error("Expected error while getting buckets")
+	}
+}
+
+func TestGetGroups(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	r.StatShards = 2
+	groups, err := r.GetGroups(SIZE)
+	if err != nil {
+		t.Error("No error expected getting groups", err)
+	}
+	if len(groups) != 5 || groups[1].Value != "4-6" || groups[1].Counts["Mutant"] != 2 || len(groups[0].Counts) != 0 {
+		t.Error("Expected 2 mutants of size 4-6. Got:", groups)
+	}
+}
+
+func TestGetUnknownGroups(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	_, err := r.GetGroups("color")
+	if err != ErrUnknownDimension {
+		t.Error("Expected unknown dimension error. Got:", err)
+	}
+}
+
+func TestIsDuplicateDna(t *testing.T) {
+	_, err := (&mockDynamoDBClientDuplicate{}).TransactWriteItems(nil)
+	if !IsDuplicateDna(err) {
+		t.Error("Expected a duplicate dna error")
+	}
+	if IsDuplicateDna(errors.New("Transact write items error")) {
+		t.Error("No duplicate dna error expected")
+	}
+}
+
+func TestParseItem(t *testing.T) {
+	item := make(map[string]*dynamodb.AttributeValue)
+	item["dna_type"] = &dynamodb.AttributeValue{S: aws.String("Human")}
+	item["type_count"] = &dynamodb.AttributeValue{N: aws.String("1")}
+	_, err := ParseItem(item)
+	if err != nil {
+		t.Error("Error expected while parsing empty item", err)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	stats, err := r.GetStats()
+	if err != nil {
+		t.Error("No error expected getting stats", err)
+	}
+	if len(stats) != 1 || stats[0].DnaType != "Human" || stats[0].Count != 2 {
+		t.Error("Expected 2 humans. Got:", stats)
+	}
+}
+
+func TestShardKey(t *testing.T) {
+	if ShardKey("Mutant", 1) != "Mutant" {
+		t.Error("Expected the plain type as key without shards")
+	}
+	for i := 0; i < 100; i++ {
+		key := ShardKey("Mutant", 4)
+		if key != "Mutant" && key != "Mutant#1" && key != "Mutant#2" && key != "Mutant#3" {
+			t.Fatal("Unexpected shard key:", key)
+		}
+	}
+}
+
+func TestShardType(t *testing.T) {
+	if ShardType("Mutant#3") != "Mutant" || ShardType("Human") != "Human" {
+		t.Error("Expected the type of the shard")
+	}
+}
+
+func TestGetStatsSumsShards(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientShards{})
+	r.ConsistentRead = true
+	stats, err := r.GetStats()
+	if err != nil {
+		t.Error("No error expected getting stats", err)
+	}
+	if len(stats) != 3 || stats[0].DnaType != "Cyborg" || stats[0].Count != 3 || stats[1].Count != 3 || stats[2].Count != 5 {
+		t.Error("Expected 3 cyborgs, 3 humans and 5 mutants, and no type that was not recorded. Got:", stats)
+	}
+}
+
+func TestBatchGetAllPagesThroughKeys(t *testing.T) {
+	db := &mockDynamoDBClientPaging{}
+	r := NewDynamoDBRepository(db)
+	r.ConsistentRead = true
+	keys := []map[string]*dynamodb.AttributeValue{}
+	for _, key := range append(ShardKeys("Human", 60), ShardKeys("Mutant", 60)...) {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{"dna_type": {S: aws.String(key)}})
+	}
+	items, err := r.BatchGetAll(STATS_TABLE, keys)
+	if err != nil {
+		t.Error("No error expected getting the items", err)
+	}
+	if len(db.inputs) != 2 || len(db.inputs[0].RequestItems[STATS_TABLE].Keys) != 100 || len(db.inputs[1].RequestItems[STATS_TABLE].Keys) != 21 {
+		t.Fatal("Expected a batch of 100 keys and another with the rest and the unprocessed one. Got:", len(db.inputs))
+	}
+	if !*db.inputs[0].RequestItems[STATS_TABLE].ConsistentRead {
+		t.Error("Expected consistent reads")
+	}
+	if len(items) != 120 {
+		t.Error("Expected every shard to be read once. Got:", len(items))
+	}
+}
+
+func TestIncrementStatWithShards(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	r.StatShards = 4
+	input := r.CreateUpdateItemInput("Mutant")
+	if ShardType(*input.Key["dna_type"].S) != "Mutant" {
+		t.Error("Expected a shard of the mutant counter. Got:", *input.Key["dna_type"].S)
+	}
+}
+
+func TestErrorOnGetStats(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientError{})
+	_, err := r.GetStats()
+	if err == nil {
+		t.Error("Expected error while getting stats")
+	}
+}
+
+func TestGetDna(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	dna, err := r.GetDna("1")
+	if err != nil {
+		t.Error("No error expected getting dna", err)
+	}
+	if dna.Type != "Mutant" || len(dna.Dna) != 1 {
+		t.Error("Expected mutant dna. Got:", dna)
+	}
+}
+
+func TestGetDnaNotFound(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	_, err := r.GetDna("2")
+	if err != ErrNotFound {
+		t.Error("Expected not found error. Got:", err)
+	}
+}
+
+func TestListDnas(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	dnas, err := r.ListDnas()
+	if err != nil {
+		t.Error("No error expected listing dnas", err)
+	}
+	if len(dnas) != 1 || dnas[0].Uuid != "1" {
+		t.Error("Expected one dna. Got:", dnas)
+	}
+}
+
+func TestErrorOnListDnas(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientError{})
+	_, err := r.ListDnas()
+	if err == nil {
+		t.Error("Expected error while listing dnas")
+	}
+}
+
+type mockDynamoDBClientUnprocessed struct {
+	dynamodbiface.DynamoDBAPI
+	calls int
+}
+
+func (m *mockDynamoDBClientUnprocessed) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	m.calls++
+	return &dynamodb.BatchGetItemOutput{
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{
+			STATS_TABLE: {Keys: input.RequestItems[STATS_TABLE].Keys},
+		},
+	}, nil
+}
+
+func TestGetStatsErrorReadingRecordedTypes(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientError{})
+	_, err := r.GetStats()
+	if err == nil {
+		t.Error("Expected error getting stats")
+	}
+}
+
+// mockDynamoDBClientRecordedShards was written with two shards, while the
+// reader is configured with one
+type mockDynamoDBClientRecordedShards struct {
+	mockDynamoDBClient
+	updates []*dynamodb.UpdateItemInput
+}
+
+func (m *mockDynamoDBClientRecordedShards) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{
+		Item: map[string]*dynamodb.AttributeValue{
+			"dna_type": {S: aws.String(SHARDS_KEY)},
+			"shards":   {N: aws.String("2")},
+		},
+	}, nil
+}
+
+func (m *mockDynamoDBClientRecordedShards) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	m.updates = append(m.updates, input)
+	return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+}
+
+func TestBatchGetAllGivesUpOnUnprocessedKeys(t *testing.T) {
+	db := &mockDynamoDBClientUnprocessed{}
+	r := NewDynamoDBRepository(db)
+	backoffs := []time.Duration{}
+	r.Sleep = func(backoff time.Duration) {
+		backoffs = append(backoffs, backoff)
+	}
+	_, err := r.BatchGetAll(STATS_TABLE, []map[string]*dynamodb.AttributeValue{{"dna_type": {S: aws.String("Mutant")}}})
+	if err != ErrUnprocessedKeys {
+		t.Error("Expected unprocessed keys error. Got:", err)
+	}
+	if db.calls != BATCH_GET_RETRIES+1 || len(backoffs) != BATCH_GET_RETRIES {
+		t.Fatal("Expected a request and every retry. Got:", db.calls, backoffs)
+	}
+	if backoffs[0] != BATCH_GET_BACKOFF || backoffs[1] != 2*BATCH_GET_BACKOFF || backoffs[BATCH_GET_RETRIES-1] != BATCH_GET_MAX_BACKOFF {
+		t.Error("Expected the backoff to double up to its cap. Got:", backoffs)
+	}
+}
+
+func TestGetBucketsReadsRecordedShards(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientRecordedShards{})
+	from := time.Date(2026, 10, 19, 5, 30, 0, 0, time.UTC)
+	buckets, err := r.GetBuckets(HOUR, from, from)
+	if err != nil {
+		t.Error("No error expected getting buckets", err)
+	}
+	if len(buckets) != 1 || buckets[0].Counts["Mutant"] != 3 {
+		t.Error("Expected the shards written before STATS_SHARDS was lowered to be read. Got:", buckets)
+	}
+}
+
+func TestRecordShards(t *testing.T) {
+	db := &mockDynamoDBClientRecordedShards{}
+	r := NewDynamoDBRepository(db)
+	err := r.RecordShards()
+	if err != nil || len(db.updates) != 0 {
+		t.Error("Expected nothing to be recorded without shards. Got:", err, len(db.updates))
+	}
+	r.StatShards = 4
+	err = r.RecordShards()
+	if err != nil {
+		t.Error("No error expected when a higher number of shards was recorded", err)
+	}
+	if len(db.updates) != 1 || *db.updates[0].Key["dna_type"].S != SHARDS_KEY || *db.updates[0].ExpressionAttributeValues[":shards"].N != "4" {
+		t.Error("Expected the number of shards to be recorded. Got:", db.updates)
+	}
+}
+
+func TestRecordTypes(t *testing.T) {
+	db := &mockDynamoDBClientRecordedShards{}
+	r := NewDynamoDBRepository(db)
+	r.DnaTypes = []string{"Mutant", "Human", "Cyborg"}
+	r.RecordTypes()
+	if len(db.updates) != 1 || *db.updates[0].Key["dna_type"].S != TYPES_KEY || *db.updates[0].UpdateExpression != "ADD dna_types :types" {
+		t.Fatal("Expected the types to be added to the recorded ones. Got:", db.updates)
+	}
+	if len(db.updates[0].ExpressionAttributeValues[":types"].SS) != 3 {
+		t.Error("Expected every type to be recorded. Got:", db.updates[0].ExpressionAttributeValues)
+	}
+}
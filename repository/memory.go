@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type MemoryRepository struct {
+	mu      sync.Mutex
+	dnas    map[string]DnaData
+	stats   map[string]int
+	buckets map[string]map[string]int
+	groups  map[string]map[string]int
+	// processed has when each processed message expires
+	processed   map[string]int64
+	quarantined map[string]QuarantinedMessage
+	Now         func() time.Time
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		dnas:    map[string]DnaData{},
+		stats:   map[string]int{},
+		buckets: map[string]map[string]int{},
+		groups:  map[string]map[string]int{},
+
+		processed:   map[string]int64{},
+		quarantined: map[string]QuarantinedMessage{},
+		Now:         time.Now,
+	}
+}
+
+func (r *MemoryRepository) SaveDna(dna DnaData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dnas[dna.Uuid] = dna
+	return nil
+}
+
+func (r *MemoryRepository) IncrementStat(dnaType string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.increment(dnaType)
+	return nil
+}
+
+func (r *MemoryRepository) RecordDna(dna DnaData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.record(dna)
+}
+
+func (r *MemoryRepository) RecordMessage(messageId string, dna DnaData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if expiresAt, ok := r.processed[messageId]; ok && expiresAt > r.Now().Unix() {
+		return ErrAlreadyProcessed
+	}
+	err := r.record(dna)
+	if err != nil {
+		return err
+	}
+	r.processed[messageId] = ExpiresAt(r.Now())
+	return nil
+}
+
+func (r *MemoryRepository) record(dna DnaData) error {
+	if _, ok := r.dnas[dna.Uuid]; ok {
+		return ErrDuplicateDna
+	}
+	r.dnas[dna.Uuid] = dna
+	r.increment(dna.Type)
+	for _, key := range DimensionKeys(dna.Dna) {
+		if r.groups[key] == nil {
+			r.groups[key] = map[string]int{}
+		}
+		r.groups[key][dna.Type]++
+	}
+	return nil
+}
+
+func (r *MemoryRepository) increment(dnaType string) {
+	r.stats[dnaType]++
+	for _, key := range BucketKeys(r.Now()) {
+		if r.buckets[key] == nil {
+			r.buckets[key] = map[string]int{}
+		}
+		r.buckets[key][dnaType]++
+	}
+}
+
+func (r *MemoryRepository) GetStats() ([]StatDB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return SortStats(r.stats), nil
+}
+
+func (r *MemoryRepository) GetDna(uuid string) (DnaData, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dna, ok := r.dnas[uuid]
+	if !ok {
+		return dna, ErrNotFound
+	}
+	return dna, nil
+}
+
+func (r *MemoryRepository) ListDnas() ([]DnaData, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dnas := []DnaData{}
+	for _, dna := range r.dnas {
+		dnas = append(dnas, dna)
+	}
+	sort.Slice(dnas, func(i, j int) bool {
+		return dnas[i].Uuid < dnas[j].Uuid
+	})
+	return dnas, nil
+}
+
+func (r *MemoryRepository) GetBuckets(granularity string, from time.Time, to time.Time) ([]Bucket, error) {
+	starts, err := BucketStarts(granularity, from, to)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	buckets := []Bucket{}
+	for _, start := range starts {
+		counts := map[string]int{}
+		for dnaType, count := range r.buckets[BucketKey(granularity, start)] {
+			counts[dnaType] = count
+		}
+		buckets = append(buckets, Bucket{
+			Start:  start,
+			Counts: counts,
+		})
+	}
+	return buckets, nil
+}
+
+func (r *MemoryRepository) GetGroups(dimension string) ([]Group, error) {
+	groups, err := NewGroups(dimension)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, group := range groups {
+		for dnaType, count := range r.groups[DimensionKey(dimension, group.Value)] {
+			group.Counts[dnaType] = count
+		}
+	}
+	return groups, nil
+}
+
+func (r *MemoryRepository) IsProcessed(messageId string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expiresAt, ok := r.processed[messageId]
+	return ok && expiresAt > r.Now().Unix(), nil
+}
+
+func (r *MemoryRepository) MarkProcessed(messageId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.processed[messageId] = ExpiresAt(r.Now())
+	return nil
+}
+
+func (r *MemoryRepository) Quarantine(message QuarantinedMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quarantined[message.MessageId] = message
+	return nil
+}
+
+func (r *MemoryRepository) GetQuarantined(messageId string) ([]QuarantinedMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := []QuarantinedMessage{}
+	for id, message := range r.quarantined {
+		if messageId == "" || id == messageId {
+			messages = append(messages, message)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].MessageId < messages[j].MessageId
+	})
+	return messages, nil
+}
+
+func (r *MemoryRepository) RemoveFromQuarantine(messageId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.quarantined, messageId)
+	return nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryRecordDna(t *testing.T) {
+	r := NewMemoryRepository()
+	err := r.RecordDna(DnaData{Uuid: "1", Type: "Mutant"})
+	if err != nil {
+		t.Error("No error expected recording dna", err)
+	}
+	err = r.RecordDna(DnaData{Uuid: "1", Type: "Mutant"})
+	if err != ErrDuplicateDna {
+		t.Error("Expected duplicate dna error. Got:", err)
+	}
+	stats, _ := r.GetStats()
+	if len(stats) != 1 || stats[0].Count != 1 {
+		t.Error("Expected the dna to be counted once. Got:", stats)
+	}
+}
+
+func TestMemoryGetStats(t *testing.T) {
+	r := NewMemoryRepository()
+	r.IncrementStat("Mutant")
+	r.IncrementStat("Human")
+	r.IncrementStat("Human")
+	stats, err := r.GetStats()
+	if err != nil {
+		t.Error("No error expected getting stats", err)
+	}
+	if len(stats) != 2 || stats[0].DnaType != "Human" || stats[0].Count != 2 || stats[1].Count != 1 {
+		t.Error("Expected 2 humans and 1 mutant. Got:", stats)
+	}
+}
+
+func TestMemoryGetDna(t *testing.T) {
+	r := NewMemoryRepository()
+	r.SaveDna(DnaData{Uuid: "1", Dna: []string{"ATGC"}, Type: "Human"})
+	dna, err := r.GetDna("1")
+	if err != nil || dna.Type != "Human" {
+		t.Error("Expected human dna. Got:", dna, err)
+	}
+	_, err = r.GetDna("2")
+	if err != ErrNotFound {
+		t.Error("Expected not found error. Got:", err)
+	}
+}
+
+func TestMemoryListDnas(t *testing.T) {
+	r := NewMemoryRepository()
+	r.SaveDna(DnaData{Uuid: "2", Type: "Human"})
+	r.SaveDna(DnaData{Uuid: "1", Type: "Mutant"})
+	dnas, err := r.ListDnas()
+	if err != nil {
+		t.Error("No error expected listing dnas", err)
+	}
+	if len(dnas) != 2 || dnas[0].Uuid != "1" {
+		t.Error("Expected dnas sorted by uuid. Got:", dnas)
+	}
+}
+
+func TestMemoryGetBuckets(t *testing.T) {
+	r := NewMemoryRepository()
+	now := time.Date(2026, 10, 19, 5, 30, 0, 0, time.UTC)
+	r.Now = func() time.Time { return now }
+	r.RecordDna(DnaData{Uuid: "1", Type: "Mutant"})
+	r.IncrementStat("Human")
+	buckets, err := r.GetBuckets(HOUR, now.Add(-time.Hour), now)
+	if err != nil {
+		t.Error("No error expected getting buckets", err)
+	}
+	if len(buckets) != 2 || len(buckets[0].Counts) != 0 || buckets[1].Counts["Mutant"] != 1 || buckets[1].Counts["Human"] != 1 {
+		t.Error("Expected 1 mutant and 1 human in the last hour. Got:", buckets)
+	}
+	days, _ := r.GetBuckets(DAY, now, now)
+	if len(days) != 1 || days[0].Counts["Mutant"] != 1 {
+		t.Error("Expected 1 mutant in the day. Got:", days)
+	}
+}
+
+func TestMemoryGetGroups(t *testing.T) {
+	r := NewMemoryRepository()
+	r.RecordDna(DnaData{Uuid: "1", Dna: []string{"AAAA", "CAGT", "TTAT", "AGAA"}, Type: "Mutant"})
+	r.RecordDna(DnaData{Uuid: "2", Dna: []string{"ATG", "CAG", "TTA"}, Type: "Human"})
+	groups, err := r.GetGroups(BASE)
+	if err != nil {
+		t.Error("No error expected getting groups", err)
+	}
+	if groups[0].Value != "A" || groups[0].Counts["Mutant"] != 1 || groups[4].Value != NONE || groups[4].Counts["Human"] != 1 {
+		t.Error("Expected a mutant with runs of A and a human without runs. Got:", groups)
+	}
+}
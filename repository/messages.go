@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+const PROCESSED_MESSAGES_TABLE = "processed_messages"
+const QUARANTINE_TABLE = "quarantined_messages"
+const PROCESSED_MESSAGE_TTL = 24 * time.Hour
+
+type ProcessedMessage struct {
+	MessageId string `json:"message_id"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type QuarantinedMessage struct {
+	MessageId     string `json:"message_id"`
+	Message       string `json:"message"`
+	Reason        string `json:"reason"`
+	QuarantinedAt string `json:"quarantined_at"`
+}
+
+// MessageStore keeps the ids of the messages already saved, so a redelivered
+// one is skipped, and the messages that could not be saved. Every repository
+// is one, so the save path runs on a single backend
+type MessageStore interface {
+	IsProcessed(messageId string) (bool, error)
+	MarkProcessed(messageId string) error
+	Quarantine(message QuarantinedMessage) error
+	// GetQuarantined returns the message with the id, or every one when the
+	// id is empty
+	GetQuarantined(messageId string) ([]QuarantinedMessage, error)
+	RemoveFromQuarantine(messageId string) error
+}
+
+// ExpiresAt is when a message marked as processed now can be forgotten
+func ExpiresAt(now time.Time) int64 {
+	return now.Add(PROCESSED_MESSAGE_TTL).Unix()
+}
+
+// RecordMessage adds the processed mark of the message right after the put of
+// the dna in the transaction of RecordDna, conditioned on the mark not
+// existing, so two deliveries of the message cannot both save and count it
+func (r *DynamoDBRepository) RecordMessage(messageId string, dna DnaData) error {
+	input, err := r.CreateTransactWriteItemsInput(dna)
+	if err != nil {
+		return err
+	}
+	av, err := dynamodbattribute.MarshalMap(ProcessedMessage{
+		MessageId: messageId,
+		ExpiresAt: ExpiresAt(r.Now()),
+	})
+	if err != nil {
+		log.Printf("Got error calling MarshalMap: %s", err)
+		return err
+	}
+	processed := &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:           aws.String(r.ProcessedTable),
+			Item:                av,
+			ConditionExpression: aws.String("attribute_not_exists(message_id)"),
+		},
+	}
+	items := []*dynamodb.TransactWriteItem{input.TransactItems[0], processed}
+	input.TransactItems = append(items, input.TransactItems[1:]...)
+	return r.transactRecord(input)
+}
+
+// IsAlreadyProcessed reports whether the transaction of RecordMessage was
+// cancelled because the message had already been marked as processed
+func IsAlreadyProcessed(err error) bool {
+	return IsConditionFailed(err, 1)
+}
+
+func (r *DynamoDBRepository) IsProcessed(messageId string) (bool, error) {
+	input := &dynamodb.GetItemInput{
+		TableName:      aws.String(r.ProcessedTable),
+		Key:            CreateMessageKey(messageId),
+		ConsistentRead: aws.Bool(true),
+	}
+	result, err := r.db.GetItem(input)
+	if err != nil {
+		log.Printf("Got error calling GetItem: %s", err)
+		return false, err
+	}
+	return result != nil && len(result.Item) > 0, nil
+}
+
+// MarkProcessed keeps the id until the TTL of the table removes it, after the
+// retries of the message are over
+func (r *DynamoDBRepository) MarkProcessed(messageId string) error {
+	av, err := dynamodbattribute.MarshalMap(ProcessedMessage{
+		MessageId: messageId,
+		ExpiresAt: ExpiresAt(r.Now()),
+	})
+	if err != nil {
+		log.Printf("Got error calling MarshalMap: %s", err)
+		return err
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(r.ProcessedTable),
+	}
+	_, err = r.db.PutItem(input)
+	if err != nil {
+		log.Printf("Got error calling PutItem: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (r *DynamoDBRepository) Quarantine(message QuarantinedMessage) error {
+	av, err := dynamodbattribute.MarshalMap(message)
+	if err != nil {
+		log.Printf("Got error calling MarshalMap: %s", err)
+		return err
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(r.QuarantineTable),
+	}
+	_, err = r.db.PutItem(input)
+	if err != nil {
+		log.Printf("Got error calling PutItem: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (r *DynamoDBRepository) GetQuarantined(messageId string) ([]QuarantinedMessage, error) {
+	messages := []QuarantinedMessage{}
+	if messageId != "" {
+		input := &dynamodb.GetItemInput{
+			TableName: aws.String(r.QuarantineTable),
+			Key:       CreateMessageKey(messageId),
+		}
+		result, err := r.db.GetItem(input)
+		if err != nil {
+			log.Printf("Got error calling GetItem: %s", err)
+			return nil, err
+		}
+		if result == nil || len(result.Item) == 0 {
+			return messages, nil
+		}
+		message := QuarantinedMessage{}
+		err = dynamodbattribute.UnmarshalMap(result.Item, &message)
+		if err != nil {
+			log.Printf("Got error unmarshalling: %s", err)
+			return nil, err
+		}
+		return append(messages, message), nil
+	}
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(r.QuarantineTable),
+	}
+	var uerr error
+	err := r.db.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		items := []QuarantinedMessage{}
+		uerr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &items)
+		messages = append(messages, items...)
+		return uerr == nil
+	})
+	if err == nil {
+		err = uerr
+	}
+	if err != nil {
+		log.Printf("Got error scanning quarantined messages: %s", err)
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *DynamoDBRepository) RemoveFromQuarantine(messageId string) error {
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.QuarantineTable),
+		Key:       CreateMessageKey(messageId),
+	}
+	_, err := r.db.DeleteItem(input)
+	if err != nil {
+		log.Printf("Got error calling DeleteItem: %s", err)
+		return err
+	}
+	return nil
+}
+
+func CreateMessageKey(messageId string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"message_id": {
+			S: aws.String(messageId),
+		},
+	}
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+type mockDynamoDBClientProcessed struct {
+	dynamodbiface.DynamoDBAPI
+	input *dynamodb.TransactWriteItemsInput
+}
+
+func (m *mockDynamoDBClientProcessed) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.input = input
+	return nil, &dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed")},
+		},
+	}
+}
+
+func CheckMessageStore(t *testing.T, store MessageStore) {
+	processed, err := store.IsProcessed("message-1")
+	if processed || err != nil {
+		t.Error("Expected the message not to be processed. Got:", processed, err)
+	}
+	store.MarkProcessed("message-1")
+	processed, _ = store.IsProcessed("message-1")
+	if !processed {
+		t.Error("Expected the message to be processed")
+	}
+	store.Quarantine(QuarantinedMessage{MessageId: "message-2", Message: "{", Reason: "malformed message"})
+	store.Quarantine(QuarantinedMessage{MessageId: "message-3", Message: "{}", Reason: "unknown dna type"})
+	store.Quarantine(QuarantinedMessage{MessageId: "message-3", Message: "{}", Reason: "unknown dna type Cyborg"})
+	messages, err := store.GetQuarantined("")
+	if err != nil || len(messages) != 2 || messages[1].Reason != "unknown dna type Cyborg" {
+		t.Error("Expected every quarantined message with its last reason. Got:", messages, err)
+	}
+	messages, _ = store.GetQuarantined("message-2")
+	if len(messages) != 1 || messages[0].Message != "{" {
+		t.Error("Expected the quarantined message. Got:", messages)
+	}
+	store.RemoveFromQuarantine("message-2")
+	messages, _ = store.GetQuarantined("message-2")
+	if len(messages) != 0 {
+		t.Error("Expected the message to be removed from quarantine. Got:", messages)
+	}
+}
+
+func TestMemoryMessages(t *testing.T) {
+	CheckMessageStore(t, NewMemoryRepository())
+}
+
+func TestSQLMessages(t *testing.T) {
+	r := OpenTestSQLRepository(t)
+	defer r.Close()
+	CheckMessageStore(t, r)
+}
+
+func TestProcessedMessagesExpire(t *testing.T) {
+	r := OpenTestSQLRepository(t)
+	defer r.Close()
+	r.MarkProcessed("message-1")
+	r.Now = func() time.Time {
+		return time.Now().Add(PROCESSED_MESSAGE_TTL + time.Minute)
+	}
+	processed, _ := r.IsProcessed("message-1")
+	if processed {
+		t.Error("Expected the message to be forgotten after its TTL")
+	}
+}
+
+func CheckMessageRecorder(t *testing.T, r interface {
+	MessageRecorder
+	Repository
+}) {
+	err := r.RecordMessage("message-1", DnaData{Uuid: "1", Dna: []string{"ATGC"}, Type: "Mutant"})
+	if err != nil {
+		t.Error("No error expected recording the message", err)
+	}
+	err = r.RecordMessage("message-1", DnaData{Uuid: "2", Dna: []string{"ATGC"}, Type: "Mutant"})
+	if err != ErrAlreadyProcessed {
+		t.Error("Expected already processed error. Got:", err)
+	}
+	err = r.RecordMessage("message-2", DnaData{Uuid: "1", Dna: []string{"ATGC"}, Type: "Mutant"})
+	if err != ErrDuplicateDna {
+		t.Error("Expected duplicate dna error. Got:", err)
+	}
+	stats, _ := r.GetStats()
+	if len(stats) != 1 || stats[0].Count != 1 {
+		t.Error("Expected the dna to be counted once. Got:", stats)
+	}
+	if _, err = r.GetDna("2"); err != ErrNotFound {
+		t.Error("Expected the dna of the processed message not to be saved. Got:", err)
+	}
+	processed, _ := r.(MessageStore).IsProcessed("message-2")
+	if processed {
+		t.Error("Expected the message of the duplicate dna not to be marked as processed")
+	}
+}
+
+func TestMemoryRecordMessage(t *testing.T) {
+	CheckMessageRecorder(t, NewMemoryRepository())
+}
+
+func TestSQLRecordMessage(t *testing.T) {
+	r := OpenTestSQLRepository(t)
+	defer r.Close()
+	CheckMessageRecorder(t, r)
+}
+
+func TestSQLRecordMessageAfterItsTTL(t *testing.T) {
+	r := OpenTestSQLRepository(t)
+	defer r.Close()
+	r.MarkProcessed("message-1")
+	r.Now = func() time.Time {
+		return time.Now().Add(PROCESSED_MESSAGE_TTL + time.Minute)
+	}
+	err := r.RecordMessage("message-1", DnaData{Uuid: "1", Dna: []string{"ATGC"}, Type: "Mutant"})
+	if err != nil {
+		t.Error("Expected the expired message to be recorded again", err)
+	}
+}
+
+func TestRecordMessageMarksItInTheTransaction(t *testing.T) {
+	db := &mockDynamoDBClientProcessed{}
+	r := NewDynamoDBRepository(db)
+	err := r.RecordMessage("message-1", DnaData{Uuid: "1", Type: "Mutant"})
+	if err != ErrAlreadyProcessed {
+		t.Error("Expected already processed error. Got:", err)
+	}
+	put := db.input.TransactItems[1].Put
+	if put == nil || *put.TableName != PROCESSED_MESSAGES_TABLE || *put.ConditionExpression != "attribute_not_exists(message_id)" {
+		t.Fatal("Expected the conditional mark of the message after the dna. Got:", db.input.TransactItems[1])
+	}
+	if *put.Item["message_id"].S != "message-1" || *db.input.TransactItems[0].Put.TableName != DNAS_TABLE {
+		t.Error("Expected the message and the dna in the transaction. Got:", db.input.TransactItems[:2])
+	}
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const TRANSACT_WRITE_LIMIT = 100
+
+// CountDnas counts the dnas of each type with a parallel scan of the dnas
+// table split in the given number of segments
+func (r *DynamoDBRepository) CountDnas(segments int) (map[string]int, error) {
+	if segments < 1 {
+		segments = 1
+	}
+	counts := make([]map[string]int, segments)
+	errs := make([]error, segments)
+	var wg sync.WaitGroup
+	for segment := 0; segment < segments; segment++ {
+		wg.Add(1)
+		go func(segment int) {
+			defer wg.Done()
+			counts[segment], errs[segment] = r.CountSegment(segment, segments)
+		}(segment)
+	}
+	wg.Wait()
+	total := map[string]int{}
+	for segment := 0; segment < segments; segment++ {
+		if errs[segment] != nil {
+			return nil, errs[segment]
+		}
+		for dnaType, count := range counts[segment] {
+			total[dnaType] += count
+		}
+	}
+	return total, nil
+}
+
+func (r *DynamoDBRepository) CountSegment(segment int, segments int) (map[string]int, error) {
+	counts := map[string]int{}
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(r.DnasTable),
+		ProjectionExpression: aws.String("#type"),
+		ExpressionAttributeNames: map[string]*string{
+			"#type": aws.String("type"),
+		},
+		Segment:       aws.Int64(int64(segment)),
+		TotalSegments: aws.Int64(int64(segments)),
+	}
+	err := r.db.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if item["type"] != nil {
+				counts[aws.StringValue(item["type"].S)]++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		log.Printf("Got error scanning segment %d: %s", segment, err)
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetStatItems returns the count stored in every item of the stats table,
+// keeping the shards apart and leaving out the recorded shards and types
+func (r *DynamoDBRepository) GetStatItems() (map[string]int, error) {
+	items := map[string]int{}
+	input := &dynamodb.ScanInput{
+		TableName:      aws.String(r.StatsTable),
+		ConsistentRead: aws.Bool(r.ConsistentRead),
+	}
+	var perr error
+	err := r.db.ScanPages(input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, i := range page.Items {
+			if key := aws.StringValue(i["dna_type"].S); key == SHARDS_KEY || key == TYPES_KEY {
+				continue
+			}
+			stat, err := ParseItem(i)
+			if err != nil {
+				perr = err
+				return false
+			}
+			items[stat.DnaType] = stat.Count
+		}
+		return true
+	})
+	if err == nil {
+		err = perr
+	}
+	if err != nil {
+		log.Printf("Got error scanning stats: %s", err)
+		return nil, err
+	}
+	return items, nil
+}
+
+// ResetStat overwrites the counter of the type with the given count in its
+// first shard and sets the rest of its shards to zero, in transactions of up
+// to TRANSACT_WRITE_LIMIT items. When a type has more shards the reset is not
+// atomic, and a failed one leaves the counter drifted until it runs again
+func (r *DynamoDBRepository) ResetStat(dnaType string, count int, shards []string) error {
+	items := []*dynamodb.TransactWriteItem{r.CreateStatPut(dnaType, count)}
+	for _, shard := range shards {
+		if shard != dnaType {
+			items = append(items, r.CreateStatPut(shard, 0))
+		}
+	}
+	for start := 0; start < len(items); start += TRANSACT_WRITE_LIMIT {
+		end := start + TRANSACT_WRITE_LIMIT
+		if end > len(items) {
+			end = len(items)
+		}
+		input := &dynamodb.TransactWriteItemsInput{
+			TransactItems: items[start:end],
+		}
+		_, err := r.db.TransactWriteItems(input)
+		if err != nil {
+			log.Printf("Got error calling TransactWriteItems: %s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *DynamoDBRepository) CreateStatPut(key string, count int) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName: aws.String(r.StatsTable),
+			Item: map[string]*dynamodb.AttributeValue{
+				"dna_type": {
+					S: aws.String(key),
+				},
+				"type_count": {
+					N: aws.String(strconv.Itoa(count)),
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,132 @@
+// Package repository stores the analyzed dnas and the count of dnas per type,
+// so the handlers can run against DynamoDB, a relational database or memory
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fpinatares/magneto/detector"
+)
+
+const DYNAMODB = "dynamodb"
+const MEMORY = "memory"
+const POSTGRES = "postgres"
+const SQLITE = "sqlite3"
+
+var ErrNotFound = errors.New("dna not found")
+var ErrDuplicateDna = errors.New("dna already saved")
+var ErrAlreadyProcessed = errors.New("message already processed")
+
+type DnaData = detector.DnaData
+
+type StatDB struct {
+	DnaType string `json:"dna_type"`
+	Count   int    `json:"type_count"`
+}
+
+type Repository interface {
+	SaveDna(dna DnaData) error
+	IncrementStat(dnaType string) error
+	GetStats() ([]StatDB, error)
+	GetDna(uuid string) (DnaData, error)
+	ListDnas() ([]DnaData, error)
+}
+
+// Recorder is implemented by the repositories that can save a dna and count
+// it as a single atomic operation. RecordDna returns ErrDuplicateDna when the
+// dna was already saved, without counting it again
+type Recorder interface {
+	RecordDna(dna DnaData) error
+}
+
+// MessageRecorder is implemented by the repositories that can also mark the
+// message of the dna as processed in the same atomic operation. RecordMessage
+// returns ErrAlreadyProcessed when another delivery of the message got there
+// first, without saving or counting anything
+type MessageRecorder interface {
+	RecordMessage(messageId string, dna DnaData) error
+}
+
+// Record saves the dna and increments the counter of its type, atomically
+// when the repository supports it
+func Record(r Repository, dna DnaData) error {
+	if recorder, ok := r.(Recorder); ok {
+		return recorder.RecordDna(dna)
+	}
+	err := r.SaveDna(dna)
+	if err != nil {
+		return err
+	}
+	return r.IncrementStat(dna.Type)
+}
+
+// WithContext returns a copy of the repository whose calls are traced as
+// children of the span of the context, when it traces them
+func WithContext(r Repository, ctx context.Context) Repository {
+	if dynamo, ok := r.(*DynamoDBRepository); ok {
+		return dynamo.WithContext(ctx)
+	}
+	return r
+}
+
+type Config struct {
+	Kind           string
+	DSN            string
+	StatShards     int
+	StatsTable     string
+	DnasTable      string
+	ConsistentRead bool
+	// DnaTypes are the types the DynamoDB repository reads and records,
+	// Human and Mutant when empty
+	DnaTypes []string
+}
+
+// ConfigFromEnv reads the REPOSITORY, REPOSITORY_DSN, STATS_SHARDS,
+// STATS_TABLE_NAME, DNAS_TABLE_NAME and STATS_CONSISTENT_READ environment
+// variables
+func ConfigFromEnv() Config {
+	shards, err := strconv.Atoi(os.Getenv("STATS_SHARDS"))
+	if err != nil {
+		shards = 1
+	}
+	consistentRead, _ := strconv.ParseBool(os.Getenv("STATS_CONSISTENT_READ"))
+	return Config{
+		Kind:           os.Getenv("REPOSITORY"),
+		DSN:            os.Getenv("REPOSITORY_DSN"),
+		StatShards:     shards,
+		StatsTable:     os.Getenv("STATS_TABLE_NAME"),
+		DnasTable:      os.Getenv("DNAS_TABLE_NAME"),
+		ConsistentRead: consistentRead,
+	}
+}
+
+// New returns the repository of the given kind, DynamoDB when it is empty.
+// The dsn is only used to connect to postgres and sqlite3
+func New(config Config, db dynamodbiface.DynamoDBAPI) (Repository, error) {
+	switch config.Kind {
+	case "", DYNAMODB:
+		r := NewDynamoDBRepository(db)
+		r.StatShards = config.StatShards
+		r.ConsistentRead = config.ConsistentRead
+		if config.StatsTable != "" {
+			r.StatsTable = config.StatsTable
+		}
+		if config.DnasTable != "" {
+			r.DnasTable = config.DnasTable
+		}
+		if len(config.DnaTypes) > 0 {
+			r.DnaTypes = config.DnaTypes
+		}
+		return r, nil
+	case MEMORY:
+		return NewMemoryRepository(), nil
+	case POSTGRES, SQLITE:
+		return OpenSQLRepository(config.Kind, config.DSN)
+	}
+	return nil, fmt.Errorf("repository %s not supported", config.Kind)
+}
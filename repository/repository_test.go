@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"os"
+	"testing"
+)
+
+type repositoryWithoutRecorder struct {
+	Repository
+	saved       int
+	incremented int
+}
+
+func (r *repositoryWithoutRecorder) SaveDna(dna DnaData) error {
+	r.saved++
+	return nil
+}
+
+func (r *repositoryWithoutRecorder) IncrementStat(dnaType string) error {
+	r.incremented++
+	return nil
+}
+
+func TestRecordWithoutRecorder(t *testing.T) {
+	r := &repositoryWithoutRecorder{}
+	err := Record(r, DnaData{Uuid: "1", Type: "Mutant"})
+	if err != nil {
+		t.Error("No error expected recording dna", err)
+	}
+	if r.saved != 1 || r.incremented != 1 {
+		t.Error("Expected the dna to be saved and counted")
+	}
+}
+
+func TestNewRepository(t *testing.T) {
+	r, err := New(Config{StatShards: 4}, &mockDynamoDBClient{})
+	if dynamo, ok := r.(*DynamoDBRepository); !ok || err != nil || dynamo.StatShards != 4 {
+		t.Error("Expected DynamoDB repository with 4 shards by default", err)
+	}
+	r, err = New(Config{Kind: MEMORY}, nil)
+	if _, ok := r.(*MemoryRepository); !ok || err != nil {
+		t.Error("Expected memory repository", err)
+	}
+}
+
+func TestNewUnsupportedRepository(t *testing.T) {
+	_, err := New(Config{Kind: "cassandra"}, nil)
+	if err == nil {
+		t.Error("Expected error creating an unsupported repository")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	os.Setenv("STATS_SHARDS", "8")
+	defer os.Unsetenv("STATS_SHARDS")
+	config := ConfigFromEnv()
+	if config.StatShards != 8 {
+		t.Error("Expected 8 shards. Got:", config.StatShards)
+	}
+}
+
+func TestConfigFromEnvWithoutShards(t *testing.T) {
+	config := ConfigFromEnv()
+	if config.StatShards != 1 {
+		t.Error("Expected 1 shard by default. Got:", config.StatShards)
+	}
+}
@@ -0,0 +1,440 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+var ErrSQLiteNotBuilt = errors.New("sqlite3 is only supported by binaries built with -tags sqlite")
+
+// The queries use $N placeholders and ON CONFLICT clauses, which both
+// Postgres and SQLite understand
+const CREATE_DNAS_TABLE = `CREATE TABLE IF NOT EXISTS dnas (
+	uuid TEXT PRIMARY KEY,
+	dna TEXT NOT NULL,
+	type TEXT NOT NULL
+)`
+const CREATE_STATS_TABLE = `CREATE TABLE IF NOT EXISTS stats (
+	dna_type TEXT PRIMARY KEY,
+	type_count INTEGER NOT NULL
+)`
+const CREATE_STATS_BUCKETS_TABLE = `CREATE TABLE IF NOT EXISTS stats_buckets (
+	bucket TEXT NOT NULL,
+	dna_type TEXT NOT NULL,
+	type_count INTEGER NOT NULL,
+	PRIMARY KEY (bucket, dna_type)
+)`
+const CREATE_STATS_DIMENSIONS_TABLE = `CREATE TABLE IF NOT EXISTS stats_dimensions (
+	dimension TEXT NOT NULL,
+	dna_type TEXT NOT NULL,
+	type_count INTEGER NOT NULL,
+	PRIMARY KEY (dimension, dna_type)
+)`
+const CREATE_PROCESSED_MESSAGES_TABLE = `CREATE TABLE IF NOT EXISTS processed_messages (
+	message_id TEXT PRIMARY KEY,
+	expires_at INTEGER NOT NULL
+)`
+const CREATE_QUARANTINED_MESSAGES_TABLE = `CREATE TABLE IF NOT EXISTS quarantined_messages (
+	message_id TEXT PRIMARY KEY,
+	message TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	quarantined_at TEXT NOT NULL
+)`
+const UPSERT_DNA = `INSERT INTO dnas (uuid, dna, type) VALUES ($1, $2, $3)
+	ON CONFLICT (uuid) DO UPDATE SET dna = excluded.dna, type = excluded.type`
+const INSERT_DNA = `INSERT INTO dnas (uuid, dna, type) VALUES ($1, $2, $3)
+	ON CONFLICT (uuid) DO NOTHING`
+const INCREMENT_STAT = `INSERT INTO stats (dna_type, type_count) VALUES ($1, 1)
+	ON CONFLICT (dna_type) DO UPDATE SET type_count = stats.type_count + 1`
+const INCREMENT_BUCKET = `INSERT INTO stats_buckets (bucket, dna_type, type_count) VALUES ($1, $2, 1)
+	ON CONFLICT (bucket, dna_type) DO UPDATE SET type_count = stats_buckets.type_count + 1`
+const INCREMENT_DIMENSION = `INSERT INTO stats_dimensions (dimension, dna_type, type_count) VALUES ($1, $2, 1)
+	ON CONFLICT (dimension, dna_type) DO UPDATE SET type_count = stats_dimensions.type_count + 1`
+const SELECT_DIMENSION = `SELECT dimension, dna_type, type_count FROM stats_dimensions
+	WHERE dimension LIKE $1`
+const SELECT_BUCKETS = `SELECT bucket, dna_type, type_count FROM stats_buckets
+	WHERE bucket >= $1 AND bucket <= $2`
+const SELECT_STATS = `SELECT dna_type, type_count FROM stats ORDER BY dna_type`
+const SELECT_DNA = `SELECT uuid, dna, type FROM dnas WHERE uuid = $1`
+const SELECT_DNAS = `SELECT uuid, dna, type FROM dnas ORDER BY uuid`
+const SELECT_PROCESSED = `SELECT COUNT(*) FROM processed_messages WHERE message_id = $1 AND expires_at > $2`
+const UPSERT_PROCESSED = `INSERT INTO processed_messages (message_id, expires_at) VALUES ($1, $2)
+	ON CONFLICT (message_id) DO UPDATE SET expires_at = excluded.expires_at`
+const INSERT_PROCESSED = `INSERT INTO processed_messages (message_id, expires_at) VALUES ($1, $2)
+	ON CONFLICT (message_id) DO UPDATE SET expires_at = excluded.expires_at
+	WHERE processed_messages.expires_at <= $3`
+const UPSERT_QUARANTINED = `INSERT INTO quarantined_messages (message_id, message, reason, quarantined_at) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (message_id) DO UPDATE SET message = excluded.message, reason = excluded.reason, quarantined_at = excluded.quarantined_at`
+const SELECT_QUARANTINED = `SELECT message_id, message, reason, quarantined_at FROM quarantined_messages
+	WHERE $1 = '' OR message_id = $1 ORDER BY message_id`
+const DELETE_QUARANTINED = `DELETE FROM quarantined_messages WHERE message_id = $1`
+
+type SQLRepository struct {
+	db  *sql.DB
+	Now func() time.Time
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{
+		db:  db,
+		Now: time.Now,
+	}
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// OpenSQLRepository connects to a postgres or sqlite3 database and creates
+// the tables when they do not exist
+func OpenSQLRepository(driver string, dsn string) (*SQLRepository, error) {
+	if driver == SQLITE && !hasDriver(SQLITE) {
+		log.Printf("Got error opening database: %s", ErrSQLiteNotBuilt)
+		return nil, ErrSQLiteNotBuilt
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		log.Printf("Got error opening database: %s", err)
+		return nil, err
+	}
+	if driver == SQLITE {
+		db.SetMaxOpenConns(1)
+	}
+	r := NewSQLRepository(db)
+	err = r.CreateSchema()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func hasDriver(name string) bool {
+	for _, driver := range sql.Drivers() {
+		if driver == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *SQLRepository) CreateSchema() error {
+	for _, statement := range []string{CREATE_DNAS_TABLE, CREATE_STATS_TABLE, CREATE_STATS_BUCKETS_TABLE, CREATE_STATS_DIMENSIONS_TABLE, CREATE_PROCESSED_MESSAGES_TABLE, CREATE_QUARANTINED_MESSAGES_TABLE} {
+		_, err := r.db.Exec(statement)
+		if err != nil {
+			log.Printf("Got error creating schema: %s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLRepository) SaveDna(dna DnaData) error {
+	bytes, err := json.Marshal(dna.Dna)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(UPSERT_DNA, dna.Uuid, string(bytes), dna.Type)
+	if err != nil {
+		log.Printf("Got error saving dna: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (r *SQLRepository) IncrementStat(dnaType string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Got error beginning transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback()
+	err = r.increment(tx, dnaType)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLRepository) increment(db execer, dnaType string) error {
+	_, err := db.Exec(INCREMENT_STAT, dnaType)
+	if err != nil {
+		log.Printf("Got error incrementing stat: %s", err)
+		return err
+	}
+	for _, key := range BucketKeys(r.Now()) {
+		_, err = db.Exec(INCREMENT_BUCKET, key, dnaType)
+		if err != nil {
+			log.Printf("Got error incrementing bucket: %s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLRepository) RecordDna(dna DnaData) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Got error beginning transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback()
+	err = r.record(tx, dna)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordMessage marks the message as processed in the transaction of the dna.
+// The mark of an expired id is replaced, and a live one is left untouched,
+// which rolls back the whole transaction
+func (r *SQLRepository) RecordMessage(messageId string, dna DnaData) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Got error beginning transaction: %s", err)
+		return err
+	}
+	defer tx.Rollback()
+	now := r.Now()
+	result, err := tx.Exec(INSERT_PROCESSED, messageId, ExpiresAt(now), now.Unix())
+	if err != nil {
+		log.Printf("Got error marking message as processed: %s", err)
+		return err
+	}
+	marked, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if marked == 0 {
+		return ErrAlreadyProcessed
+	}
+	err = r.record(tx, dna)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLRepository) record(tx execer, dna DnaData) error {
+	bytes, err := json.Marshal(dna.Dna)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(INSERT_DNA, dna.Uuid, string(bytes), dna.Type)
+	if err != nil {
+		log.Printf("Got error saving dna: %s", err)
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return ErrDuplicateDna
+	}
+	err = r.increment(tx, dna.Type)
+	if err != nil {
+		return err
+	}
+	for _, key := range DimensionKeys(dna.Dna) {
+		_, err = tx.Exec(INCREMENT_DIMENSION, key, dna.Type)
+		if err != nil {
+			log.Printf("Got error incrementing dimension: %s", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLRepository) GetStats() ([]StatDB, error) {
+	rows, err := r.db.Query(SELECT_STATS)
+	if err != nil {
+		log.Printf("Got error querying stats: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+	stats := []StatDB{}
+	for rows.Next() {
+		stat := StatDB{}
+		err = rows.Scan(&stat.DnaType, &stat.Count)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+func (r *SQLRepository) GetDna(uuid string) (DnaData, error) {
+	dna, err := scanDna(r.db.QueryRow(SELECT_DNA, uuid))
+	if err == sql.ErrNoRows {
+		return dna, ErrNotFound
+	}
+	return dna, err
+}
+
+func (r *SQLRepository) ListDnas() ([]DnaData, error) {
+	rows, err := r.db.Query(SELECT_DNAS)
+	if err != nil {
+		log.Printf("Got error querying dnas: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+	dnas := []DnaData{}
+	for rows.Next() {
+		dna, err := scanDna(rows)
+		if err != nil {
+			return nil, err
+		}
+		dnas = append(dnas, dna)
+	}
+	return dnas, rows.Err()
+}
+
+func (r *SQLRepository) GetBuckets(granularity string, from time.Time, to time.Time) ([]Bucket, error) {
+	starts, err := BucketStarts(granularity, from, to)
+	if err != nil {
+		return nil, err
+	}
+	buckets := []Bucket{}
+	indexes := map[string]int{}
+	for i, start := range starts {
+		buckets = append(buckets, Bucket{
+			Start:  start,
+			Counts: map[string]int{},
+		})
+		indexes[BucketKey(granularity, start)] = i
+	}
+	first := BucketKey(granularity, starts[0])
+	last := BucketKey(granularity, starts[len(starts)-1])
+	rows, err := r.db.Query(SELECT_BUCKETS, first, last)
+	if err != nil {
+		log.Printf("Got error querying buckets: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, dnaType string
+		var count int
+		err = rows.Scan(&key, &dnaType, &count)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := indexes[key]; ok {
+			buckets[i].Counts[dnaType] = count
+		}
+	}
+	return buckets, rows.Err()
+}
+
+func (r *SQLRepository) GetGroups(dimension string) ([]Group, error) {
+	groups, err := NewGroups(dimension)
+	if err != nil {
+		return nil, err
+	}
+	indexes := map[string]int{}
+	for i, group := range groups {
+		indexes[DimensionKey(dimension, group.Value)] = i
+	}
+	rows, err := r.db.Query(SELECT_DIMENSION, DimensionKey(dimension, "%"))
+	if err != nil {
+		log.Printf("Got error querying dimension: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, dnaType string
+		var count int
+		err = rows.Scan(&key, &dnaType, &count)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := indexes[key]; ok {
+			groups[i].Counts[dnaType] = count
+		}
+	}
+	return groups, rows.Err()
+}
+
+// IsProcessed ignores the ids past their expiration, like the TTL of the
+// DynamoDB table does
+func (r *SQLRepository) IsProcessed(messageId string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(SELECT_PROCESSED, messageId, r.Now().Unix()).Scan(&count)
+	if err != nil {
+		log.Printf("Got error querying processed messages: %s", err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *SQLRepository) MarkProcessed(messageId string) error {
+	_, err := r.db.Exec(UPSERT_PROCESSED, messageId, ExpiresAt(r.Now()))
+	if err != nil {
+		log.Printf("Got error marking message as processed: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (r *SQLRepository) Quarantine(message QuarantinedMessage) error {
+	_, err := r.db.Exec(UPSERT_QUARANTINED, message.MessageId, message.Message, message.Reason, message.QuarantinedAt)
+	if err != nil {
+		log.Printf("Got error quarantining message: %s", err)
+		return err
+	}
+	return nil
+}
+
+func (r *SQLRepository) GetQuarantined(messageId string) ([]QuarantinedMessage, error) {
+	rows, err := r.db.Query(SELECT_QUARANTINED, messageId)
+	if err != nil {
+		log.Printf("Got error querying quarantined messages: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+	messages := []QuarantinedMessage{}
+	for rows.Next() {
+		message := QuarantinedMessage{}
+		err = rows.Scan(&message.MessageId, &message.Message, &message.Reason, &message.QuarantinedAt)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (r *SQLRepository) RemoveFromQuarantine(messageId string) error {
+	_, err := r.db.Exec(DELETE_QUARANTINED, messageId)
+	if err != nil {
+		log.Printf("Got error removing message from quarantine: %s", err)
+		return err
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDna(row scanner) (DnaData, error) {
+	dna := DnaData{}
+	var sequences string
+	err := row.Scan(&dna.Uuid, &sequences, &dna.Type)
+	if err != nil {
+		return dna, err
+	}
+	err = json.Unmarshal([]byte(sequences), &dna.Dna)
+	return dna, err
+}
+
+func (r *SQLRepository) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func OpenTestSQLRepository(t *testing.T) *SQLRepository {
+	r, err := OpenSQLRepository(SQLITE, ":memory:")
+	if err == ErrSQLiteNotBuilt {
+		t.Skip("sqlite3 is only built with -tags sqlite")
+	}
+	if err != nil {
+		t.Fatal("No error expected opening sqlite database", err)
+	}
+	return r
+}
+
+func TestSQLRecordDna(t *testing.T) {
+	r := OpenTestSQLRepository(t)
+	defer r.Close()
+	err := r.RecordDna(DnaData{Uuid: "1", Dna: []string{"ATGC"}, Type: "Mutant"})
+	if err != nil {
+		t.Error("No error expected recording dna", err)
+	}
+	err = r.RecordDna(DnaData{Uuid: "1", Dna: []string{"ATGC"}, Type: "Mutant"})
+	if err != ErrDuplicateDna {
+		t.Error("Expected duplicate dna error. Got:", err)
+	}
+	stats, _ := r.GetStats()
+	if len(stats) != 1 || stats[0].Count != 1 {
+		t.Error("Expected the dna to be counted once. Got:", stats)
+	}
+}
+
+func TestSQLGetStats(t *testing.T) {
+	r := OpenTestSQLRepository(t)
+	defer r.Close()
+	r.IncrementStat("Mutant")
+	r.IncrementStat("Human")
+	r.IncrementStat("Human")
+	stats, err := r.GetStats()
+	if err != nil {
+		t.Error("No error expected getting stats", err)
+	}
+	if len(stats) != 2 || stats[0].DnaType != "Human" || stats[0].Count != 2 || stats[1].Count != 1 {
+		t.Error("Expected 2 humans and 1 mutant. Got:", stats)
+	}
+}
+
+func TestSQLGetDna(t *testing.T) {
+	r := OpenTestSQLRepository(t)
+	defer r.Close()
+	r.SaveDna(DnaData{Uuid: "1", Dna: []string{"ATGC", "CAGT"}, Type: "Human"})
+	dna, err := r.GetDna("1")
+	if err != nil || dna.Type != "Human" || len(dna.Dna) != 2 {
+		t.Error("Expected human dna. Got:", dna, err)
+	}
+	_, err = r.GetDna("2")
+	if err != ErrNotFound {
+		t.Error("Expected not found error. Got:", err)
+	}
+}
+
+func TestSQLSaveDnaOverwrites(t *testing.T) {
+	r := OpenTestSQLRepository(t)
+	defer r.Close()
+	r.SaveDna(DnaData{Uuid: "1", Dna: []string{"ATGC"}, Type: "Human"})
+	r.SaveDna(DnaData{Uuid: "1", Dna: []string{"ATGC"}, Type: "Mutant"})
+	dnas, err := r.ListDnas()
+	if err != nil {
+		t.Error("No error expected listing dnas", err)
+	}
+	if len(dnas) != 1 || dnas[0].Type != "Mutant" {
+		t.Error("Expected the dna to be overwritten. Got:", dnas)
+	}
+}
+
+func TestSQLGetBuckets(t *testing.T) {
+	r := OpenTestSQLRepository(t)
+	defer r.Close()
+	now := time.Date(2026, 10, 19, 5, 30, 0, 0, time.UTC)
+	r.Now = func() time.Time { return now }
+	r.RecordDna(DnaData{Uuid: "1", Dna: []string{"ATGC"}, Type: "Mutant"})
+	r.IncrementStat("Human")
+	buckets, err := r.GetBuckets(HOUR, now.Add(-time.Hour), now)
+	if err != nil {
+		t.Error("No error expected getting buckets", err)
+	}
+	if len(buckets) != 2 || len(buckets[0].Counts) != 0 || buckets[1].Counts["Mutant"] != 1 || buckets[1].Counts["Human"] != 1 {
+		t.Error("Expected 1 mutant and 1 human in the last hour. Got:", buckets)
+	}
+	days, _ := r.GetBuckets(DAY, now, now)
+	if len(days) != 1 || days[0].Counts["Mutant"] != 1 {
+		t.Error("Expected 1 mutant in the day. Got:", days)
+	}
+}
+
+func TestSQLGetGroups(t *testing.T) {
+	r := OpenTestSQLRepository(t)
+	defer r.Close()
+	r.RecordDna(DnaData{Uuid: "1", Dna: []string{"AAAA", "CAGT", "TTAT", "AGAA"}, Type: "Mutant"})
+	r.RecordDna(DnaData{Uuid: "2", Dna: []string{"ATG", "CAG", "TTA"}, Type: "Human"})
+	groups, err := r.GetGroups(SIZE)
+	if err != nil {
+		t.Error("No error expected getting groups", err)
+	}
+	if groups[0].Counts["Human"] != 1 || groups[1].Counts["Mutant"] != 1 || len(groups[2].Counts) != 0 {
+		t.Error("Expected a human of size 3 and a mutant of size 4. Got:", groups)
+	}
+}
+
+func TestOpenSQLiteWithoutTheDriver(t *testing.T) {
+	if hasDriver(SQLITE) {
+		t.Skip("built with -tags sqlite")
+	}
+	_, err := OpenSQLRepository(SQLITE, ":memory:")
+	if err != ErrSQLiteNotBuilt {
+		t.Error("Expected sqlite3 not to be built in. Got:", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package repository
+
+// The sqlite3 driver needs cgo, so it is only built with -tags sqlite and the
+// lambdas, which are built without cgo, do not carry it
+import _ "github.com/mattn/go-sqlite3"
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const PROCESSED_EVENTS_TABLE = "processed_events"
+
+// PROCESSED_EVENT_TTL matches the retention of the stream records
+const PROCESSED_EVENT_TTL = 24 * time.Hour
+
+var ErrAlreadyApplied = errors.New("event already applied")
+
+// Change adds Delta to the counters of the dna. Only the changes of inserted
+// dnas count in the hour and day buckets, since removing a dna does not change
+// when it was analyzed
+type Change struct {
+	Dna      DnaData
+	Delta    int
+	Analyzed bool
+}
+
+// ApplyChanges updates the counters of every change in a single transaction,
+// which also records the event id, so a redelivered event cancels it instead
+// of being counted twice
+func (r *DynamoDBRepository) ApplyChanges(eventId string, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	_, err := r.db.TransactWriteItems(r.CreateChangesTransactWriteItemsInput(eventId, changes))
+	if err != nil {
+		if IsDuplicateDna(err) {
+			return ErrAlreadyApplied
+		}
+		log.Printf("Got error calling TransactWriteItems: %s", err)
+		return err
+	}
+	return nil
+}
+
+// CreateChangesTransactWriteItemsInput adds up the deltas of the changes per
+// counter before building the updates, since a transaction can not have two
+// operations on the same item. A modified dna is a -1 and a +1 change, which
+// share the items of its dimensions, and of its type when it did not change
+func (r *DynamoDBRepository) CreateChangesTransactWriteItemsInput(eventId string, changes []Change) *dynamodb.TransactWriteItemsInput {
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Put: &dynamodb.Put{
+					TableName: aws.String(r.EventsTable),
+					Item: map[string]*dynamodb.AttributeValue{
+						"event_id": {
+							S: aws.String(eventId),
+						},
+						"expires_at": {
+							N: aws.String(strconv.FormatInt(r.Now().Add(PROCESSED_EVENT_TTL).Unix(), 10)),
+						},
+					},
+					ConditionExpression: aws.String("attribute_not_exists(event_id)"),
+				},
+			},
+		},
+	}
+	counters := NewCounters()
+	for _, change := range changes {
+		counters.Add(CounterItem{r.StatsTable, "dna_type", change.Dna.Type}, "type_count", change.Delta)
+		if change.Analyzed {
+			for _, key := range BucketKeys(r.Now()) {
+				counters.Add(CounterItem{r.BucketsTable, "bucket", key}, change.Dna.Type, change.Delta)
+			}
+		}
+		for _, key := range DimensionKeys(change.Dna.Dna) {
+			counters.Add(CounterItem{r.DimensionsTable, "dimension", key}, change.Dna.Type, change.Delta)
+		}
+	}
+	for _, update := range counters.Updates(r.StatShards) {
+		input.TransactItems = append(input.TransactItems, ToTransactWriteItem(update))
+	}
+	return input
+}
+
+// CounterItem is an item that holds counters, before picking its shard
+type CounterItem struct {
+	Table   string
+	KeyName string
+	Key     string
+}
+
+// Counters adds up the deltas of the attributes of every item, in the order
+// they were first added
+type Counters struct {
+	items      []CounterItem
+	attributes map[CounterItem][]string
+	deltas     map[CounterItem]map[string]int
+}
+
+func NewCounters() *Counters {
+	return &Counters{
+		attributes: map[CounterItem][]string{},
+		deltas:     map[CounterItem]map[string]int{},
+	}
+}
+
+func (c *Counters) Add(item CounterItem, attribute string, delta int) {
+	if _, ok := c.deltas[item]; !ok {
+		c.items = append(c.items, item)
+		c.deltas[item] = map[string]int{}
+	}
+	if _, ok := c.deltas[item][attribute]; !ok {
+		c.attributes[item] = append(c.attributes[item], attribute)
+	}
+	c.deltas[item][attribute] += delta
+}
+
+// Updates has an update per item, on one of its shards, with every attribute
+// whose delta is not zero. The items left without changes are dropped
+func (c *Counters) Updates(shards int) []*dynamodb.UpdateItemInput {
+	updates := []*dynamodb.UpdateItemInput{}
+	for _, item := range c.items {
+		names := map[string]*string{}
+		values := map[string]*dynamodb.AttributeValue{}
+		adds := []string{}
+		for _, attribute := range c.attributes[item] {
+			delta := c.deltas[item][attribute]
+			if delta == 0 {
+				continue
+			}
+			i := strconv.Itoa(len(adds))
+			names["#c"+i] = aws.String(attribute)
+			values[":c"+i] = &dynamodb.AttributeValue{
+				N: aws.String(strconv.Itoa(delta)),
+			}
+			adds = append(adds, "#c"+i+" :c"+i)
+		}
+		if len(adds) == 0 {
+			continue
+		}
+		updates = append(updates, &dynamodb.UpdateItemInput{
+			TableName: aws.String(item.Table),
+			Key: map[string]*dynamodb.AttributeValue{
+				item.KeyName: {
+					S: aws.String(ShardKey(item.Key, shards)),
+				},
+			},
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			UpdateExpression:          aws.String("ADD " + strings.Join(adds, ", ")),
+		})
+	}
+	return updates
+}
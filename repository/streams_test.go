@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"testing"
+)
+
+func TestApplyChanges(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	err := r.ApplyChanges("1", []Change{{Dna: DnaData{Type: "Mutant"}, Delta: 1, Analyzed: true}})
+	if err != nil {
+		t.Error("No error expected applying changes", err)
+	}
+}
+
+func TestApplyChangesAlreadyApplied(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientDuplicate{})
+	err := r.ApplyChanges("1", []Change{{Dna: DnaData{Type: "Mutant"}, Delta: 1}})
+	if err != ErrAlreadyApplied {
+		t.Error("Expected already applied error. Got:", err)
+	}
+}
+
+func TestErrorOnApplyChanges(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClientError{})
+	err := r.ApplyChanges("1", []Change{{Dna: DnaData{Type: "Mutant"}, Delta: -1}})
+	if err == nil {
+		t.Error("Expected error applying changes")
+	}
+}
+
+func TestCreateChangesTransactWriteItemsInput(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	input := r.CreateChangesTransactWriteItemsInput("1", []Change{
+		{Dna: DnaData{Type: "Human"}, Delta: -1},
+		{Dna: DnaData{Type: "Mutant"}, Delta: 1, Analyzed: true},
+	})
+	if len(input.TransactItems) != 3+len(Granularities)+len(Dimensions) {
+		t.Fatal("Expected an item per counter. Got:", len(input.TransactItems))
+	}
+	put := input.TransactItems[0].Put
+	if put == nil || *put.TableName != PROCESSED_EVENTS_TABLE || *put.Item["event_id"].S != "1" {
+		t.Error("Expected a conditional put of the event id")
+	}
+	stats := map[string]string{}
+	for _, item := range input.TransactItems[1:] {
+		update := item.Update
+		if *update.TableName == STATS_TABLE {
+			stats[*update.Key["dna_type"].S] = *update.ExpressionAttributeValues[":c0"].N
+		}
+	}
+	if stats["Human"] != "-1" || stats["Mutant"] != "1" {
+		t.Error("Expected the human counter to be decremented and the mutant one incremented. Got:", stats)
+	}
+	for _, item := range input.TransactItems[1:] {
+		update := item.Update
+		if *update.TableName == STATS_BUCKETS_TABLE && *update.UpdateExpression != "ADD #c0 :c0" {
+			t.Error("Expected only the mutant counter in the buckets. Got:", *update.UpdateExpression)
+		}
+		if *update.TableName == STATS_DIMENSIONS_TABLE && *update.UpdateExpression != "ADD #c0 :c0, #c1 :c1" {
+			t.Error("Expected both counters in a single update of the dimension. Got:", *update.UpdateExpression)
+		}
+	}
+}
+
+func TestChangesOfModifiedDnaUpdateEachItemOnce(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	r.StatShards = 4
+	input := r.CreateChangesTransactWriteItemsInput("1", []Change{
+		{Dna: DnaData{Type: "Human", Dna: []string{"ATGC", "CAGT", "TTAT", "AGAC"}}, Delta: -1},
+		{Dna: DnaData{Type: "Human", Dna: []string{"AAAA", "CAGT", "TTAT", "AGAC"}}, Delta: 1},
+	})
+	keys := map[string]bool{}
+	for _, item := range input.TransactItems[1:] {
+		update := item.Update
+		for name, value := range update.Key {
+			key := *update.TableName + "/" + name + "/" + *value.S
+			if keys[key] {
+				t.Error("Expected a single operation on the item. Got it twice:", key)
+			}
+			keys[key] = true
+		}
+		if *update.TableName == STATS_TABLE {
+			t.Error("Expected no update of the stats when the type did not change")
+		}
+		for _, value := range update.ExpressionAttributeValues {
+			if *value.N == "0" {
+				t.Error("Expected no attribute without change. Got:", *update.UpdateExpression)
+			}
+		}
+	}
+}
+
+func TestChangesThatCancelOutHaveNoUpdates(t *testing.T) {
+	r := NewDynamoDBRepository(&mockDynamoDBClient{})
+	dna := DnaData{Type: "Mutant", Dna: []string{"AAAA", "CCCC", "TTAT", "AGAC"}}
+	input := r.CreateChangesTransactWriteItemsInput("1", []Change{
+		{Dna: dna, Delta: -1},
+		{Dna: dna, Delta: 1},
+	})
+	if len(input.TransactItems) != 1 {
+		t.Error("Expected only the put of the event id. Got:", len(input.TransactItems))
+	}
+}
@@ -0,0 +1,119 @@
+// Package router dispatches the API Gateway requests by method and path, so a
+// single lambda can serve the detector, the stats and the lookup
+package router
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/server"
+)
+
+type Router struct {
+	routes []server.Route
+}
+
+// New matches the routes in order. A segment of a path like {uuid} matches
+// any value, which is passed as a path parameter
+func New(routes []server.Route) *Router {
+	return &Router{
+		routes: routes,
+	}
+}
+
+// Routes are the resources of the API, served by the detector and the stats
+// handlers
+func Routes(detect server.Handler, stats server.Handler) []server.Route {
+	return []server.Route{
+		{Method: http.MethodPost, Path: "/mutant", Handler: detect},
+		{Method: http.MethodGet, Path: "/stats", Handler: stats},
+		{Method: http.MethodGet, Path: "/stats/metrics", Handler: stats},
+		{Method: http.MethodGet, Path: "/v2/stats", Handler: stats},
+		{Method: http.MethodGet, Path: "/dnas/{uuid}", Handler: stats},
+	}
+}
+
+// Handle answers 404 when no route has the path, and 405 with the allowed
+// methods when none of them has the method
+func (r *Router) Handle(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	path := RequestPath(req)
+	allowed := []string{}
+	for _, route := range r.routes {
+		params, ok := Match(route.Path, path)
+		if !ok {
+			continue
+		}
+		if route.Method != "" && route.Method != req.HTTPMethod {
+			allowed = append(allowed, route.Method)
+			continue
+		}
+		if len(params) > 0 {
+			if req.PathParameters == nil {
+				req.PathParameters = map[string]string{}
+			}
+			for name, value := range params {
+				req.PathParameters[name] = value
+			}
+		}
+		req.Resource = route.Path
+		return route.Handler(req)
+	}
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		response, err := Respond(http.StatusMethodNotAllowed)
+		response.Headers["Allow"] = strings.Join(Unique(allowed), ", ")
+		return response, err
+	}
+	return Respond(http.StatusNotFound)
+}
+
+// RequestPath is the path of the request without a trailing slash
+func RequestPath(req events.APIGatewayProxyRequest) string {
+	path := req.Path
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+func Match(pattern string, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(segment, "{}")] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func Unique(values []string) []string {
+	unique := []string{}
+	for i, value := range values {
+		if i == 0 || values[i-1] != value {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+func Respond(status int) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{},
+		Body:       http.StatusText(status),
+	}, nil
+}
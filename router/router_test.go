@@ -0,0 +1,68 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/server"
+)
+
+func echo(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       req.Resource + " " + req.PathParameters["uuid"],
+	}, nil
+}
+
+func TestHandleRoutes(t *testing.T) {
+	r := New(Routes(echo, echo))
+	response, _ := r.Handle(events.APIGatewayProxyRequest{HTTPMethod: "POST", Path: "/mutant"})
+	if response.StatusCode != 200 || response.Body != "/mutant " {
+		t.Error("Expected the detect route. Got:", response.StatusCode, response.Body)
+	}
+	response, _ = r.Handle(events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/dnas/1/"})
+	if response.StatusCode != 200 || response.Body != "/dnas/{uuid} 1" {
+		t.Error("Expected the lookup route with its uuid. Got:", response.StatusCode, response.Body)
+	}
+}
+
+func TestHandleMethodNotAllowed(t *testing.T) {
+	r := New(Routes(echo, echo))
+	response, _ := r.Handle(events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/mutant"})
+	if response.StatusCode != 405 || response.Headers["Allow"] != "POST" {
+		t.Error("405 - Method Not Allowed http status code expected with the allowed methods. Got:", response.StatusCode, response.Headers)
+	}
+}
+
+func TestHandleAllowsEveryMethodOfThePath(t *testing.T) {
+	r := New([]server.Route{
+		{Method: http.MethodPost, Path: "/dnas", Handler: echo},
+		{Method: http.MethodGet, Path: "/dnas", Handler: echo},
+	})
+	response, _ := r.Handle(events.APIGatewayProxyRequest{HTTPMethod: "DELETE", Path: "/dnas"})
+	if response.StatusCode != 405 || response.Headers["Allow"] != "GET, POST" {
+		t.Error("Expected every allowed method. Got:", response.StatusCode, response.Headers)
+	}
+}
+
+func TestHandleNotFound(t *testing.T) {
+	r := New(Routes(echo, echo))
+	for _, path := range []string{"/other", "/dnas/", "/dnas/1/2"} {
+		response, _ := r.Handle(events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: path})
+		if response.StatusCode != 404 {
+			t.Error("404 - Not Found http status code expected for", path, "Got:", response.StatusCode)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	params, ok := Match("/dnas/{uuid}", "/dnas/abc")
+	if !ok || params["uuid"] != "abc" {
+		t.Error("Expected the path parameter. Got:", params, ok)
+	}
+	_, ok = Match("/stats", "/stats/metrics")
+	if ok {
+		t.Error("Expected paths of different length not to match")
+	}
+}
@@ -0,0 +1,184 @@
+// Package server runs the lambda handlers on a plain net/http server, for
+// on-premises deployments and local development
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/google/uuid"
+)
+
+const SHUTDOWN_TIMEOUT = 10 * time.Second
+
+// READ_HEADER_TIMEOUT closes the connections of the clients that do not send
+// the headers of their request in time
+const READ_HEADER_TIMEOUT = 10 * time.Second
+
+type Handler func(events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Route is a handler for a path and method. An empty method accepts any
+type Route struct {
+	Method  string
+	Path    string
+	Handler Handler
+}
+
+// Adapt translates the http request into an API Gateway one, and the response
+// of the handler back
+func Adapt(handler Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := NewRequest(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		response, err := handler(req)
+		if err != nil {
+			log.Printf("Got error handling %s %s: %s", r.Method, r.URL.Path, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		WriteResponse(w, response)
+	})
+}
+
+// NewMux serves every route on its path, answering 405 to other methods
+func NewMux(routes []Route) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		route := route
+		handler := Adapt(route.Handler)
+		mux.Handle(route.Path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route.Method != "" && r.Method != route.Method {
+				w.Header().Set("Allow", route.Method)
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}))
+	}
+	return mux
+}
+
+// NewRequest gives every request an id, like API Gateway does, which is
+// logged with its entries and answered with its errors
+func NewRequest(r *http.Request) (events.APIGatewayProxyRequest, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, err
+	}
+	req := events.APIGatewayProxyRequest{
+		Resource:                        r.URL.Path,
+		Path:                            r.URL.Path,
+		HTTPMethod:                      r.Method,
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string{},
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string{},
+		Body:                            string(body),
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID:  uuid.New().String(),
+			HTTPMethod: r.Method,
+			Path:       r.URL.Path,
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  SourceIP(r.RemoteAddr),
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+	for name, values := range r.Header {
+		// only the lambdas set the id of their request
+		if strings.EqualFold(name, logging.INVOCATION_ID_HEADER) {
+			continue
+		}
+		req.Headers[name] = strings.Join(values, ",")
+		req.MultiValueHeaders[name] = values
+	}
+	for name, values := range r.URL.Query() {
+		req.QueryStringParameters[name] = values[len(values)-1]
+		req.MultiValueQueryStringParameters[name] = values
+	}
+	return req, nil
+}
+
+func SourceIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+func WriteResponse(w http.ResponseWriter, response events.APIGatewayProxyResponse) {
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range response.MultiValueHeaders {
+		w.Header()[http.CanonicalHeaderKey(name)] = values
+	}
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			log.Printf("Got error decoding body: %s", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		body = decoded
+	}
+	status := response.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// AddressFlag defines the -addr flag of a serve command, which defaults to the
+// LISTEN_ADDRESS environment variable or else to the given address
+func AddressFlag(flags *flag.FlagSet, fallback string) *string {
+	if address := os.Getenv("LISTEN_ADDRESS"); address != "" {
+		fallback = address
+	}
+	return flags.String("addr", fallback, "address to listen on")
+}
+
+// Serve listens until the process gets SIGINT or SIGTERM, then waits for the
+// requests in flight before returning
+func Serve(address string, handler http.Handler) error {
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: READ_HEADER_TIMEOUT,
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	errs := make(chan error, 1)
+	go func() {
+		logging.Default().Info("Listening", "address", address)
+		errs <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-stop:
+	}
+	logging.Default().Info("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
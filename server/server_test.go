@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func echo(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusCreated,
+		Headers:    map[string]string{"X-Path": req.Path, "X-Type": req.Headers["Content-Type"]},
+		Body:       req.QueryStringParameters["name"] + ":" + req.Body,
+	}, nil
+}
+
+func TestNewRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/mutant?a=1&a=2", strings.NewReader("body"))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Lambda-Request-Id", "spoofed")
+	req, err := NewRequest(r)
+	if err != nil {
+		t.Error("No error expected translating the request", err)
+	}
+	if req.HTTPMethod != "POST" || req.Path != "/mutant" || req.Body != "body" || req.Headers["Content-Type"] != "application/json" {
+		t.Error("Unexpected request:", req)
+	}
+	if _, ok := req.Headers["X-Lambda-Request-Id"]; ok {
+		t.Error("Expected the id of a lambda request sent by the client dropped. Got:", req.Headers)
+	}
+	if req.QueryStringParameters["a"] != "2" || len(req.MultiValueQueryStringParameters["a"]) != 2 {
+		t.Error("Expected every value of the query parameter. Got:", req.MultiValueQueryStringParameters)
+	}
+	other, _ := NewRequest(httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if req.RequestContext.RequestID == "" || req.RequestContext.RequestID == other.RequestContext.RequestID {
+		t.Error("Expected an id for every request. Got:", req.RequestContext.RequestID, other.RequestContext.RequestID)
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/echo?name=magneto", strings.NewReader("hi"))
+	r.Header.Set("Content-Type", "text/plain")
+	Adapt(echo).ServeHTTP(w, r)
+	if w.Code != 201 || w.Body.String() != "magneto:hi" || w.Header().Get("X-Path") != "/echo" || w.Header().Get("X-Type") != "text/plain" {
+		t.Error("Unexpected response:", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestWriteBase64Response(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteResponse(w, events.APIGatewayProxyResponse{
+		StatusCode:      200,
+		Body:            base64.StdEncoding.EncodeToString([]byte("binary")),
+		IsBase64Encoded: true,
+	})
+	if w.Body.String() != "binary" {
+		t.Error("Expected the decoded body. Got:", w.Body.String())
+	}
+}
+
+func TestNewMuxMethodNotAllowed(t *testing.T) {
+	mux := NewMux([]Route{{Method: http.MethodPost, Path: "/echo", Handler: echo}})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/echo", nil))
+	if w.Code != 405 || w.Header().Get("Allow") != "POST" {
+		t.Error("405 - Method Not Allowed http status code expected. Got:", w.Code)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if w.Code != 404 {
+		t.Error("404 - Not Found http status code expected. Got:", w.Code)
+	}
+}
+
+func TestSourceIP(t *testing.T) {
+	if SourceIP("10.0.0.1:1234") != "10.0.0.1" || SourceIP("[::1]:1234") != "::1" {
+		t.Error("Expected the host of the remote address")
+	}
+}
@@ -0,0 +1,147 @@
+package stats
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	DEFAULT_CACHE_TTL     = 10 * time.Second
+	DEFAULT_CACHE_CONTROL = "public, max-age=10"
+)
+
+// StatCache keeps the last computed stat for a while, so warm lambdas do not
+// read the counters on every request
+type StatCache struct {
+	TTL     time.Duration
+	Now     func() time.Time
+	mu      sync.Mutex
+	stat    Stat
+	expires time.Time
+}
+
+func NewStatCache(ttl time.Duration) *StatCache {
+	return &StatCache{
+		TTL: ttl,
+		Now: time.Now,
+	}
+}
+
+func (c *StatCache) Get() (Stat, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Now().Before(c.expires) {
+		return c.stat, true
+	}
+	return Stat{}, false
+}
+
+func (c *StatCache) Set(stat Stat) {
+	if c.TTL <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stat = stat
+	c.expires = c.Now().Add(c.TTL)
+}
+
+// CacheConfigFromEnv reads the STATS_CACHE_TTL and STATS_CACHE_CONTROL
+// environment variables. A TTL of 0 disables the cache and an empty
+// Cache-Control omits the header
+func CacheConfigFromEnv() (time.Duration, string) {
+	ttl := DEFAULT_CACHE_TTL
+	if value, ok := os.LookupEnv("STATS_CACHE_TTL"); ok {
+		parsed, err := time.ParseDuration(value)
+		if err == nil {
+			ttl = parsed
+		}
+	}
+	cacheControl := DEFAULT_CACHE_CONTROL
+	if value, ok := os.LookupEnv("STATS_CACHE_CONTROL"); ok {
+		cacheControl = value
+	}
+	return ttl, cacheControl
+}
+
+// GetStat computes the stat from the repository, unless there is a fresh one
+// in the cache
+func (d *Handler) GetStat() (Stat, error) {
+	if d.cache != nil {
+		if stat, ok := d.cache.Get(); ok {
+			return stat, nil
+		}
+	}
+	stats, err := d.GetStatsFromDB()
+	if err != nil {
+		return Stat{}, err
+	}
+	var stat Stat
+	err = stat.SetValues(stats)
+	if err != nil {
+		return Stat{}, err
+	}
+	if d.cache != nil {
+		d.cache.Set(stat)
+	}
+	return stat, nil
+}
+
+// ETag is derived from the counts and the variant of the response, since the
+// same counts are rendered differently by each version
+func ETag(stat Stat, variant string) string {
+	hash := sha256.New()
+	fmt.Fprint(hash, variant)
+	for _, dnaType := range RegisteredTypes() {
+		fmt.Fprintf(hash, "|%s=%d", dnaType, stat.Counts[dnaType])
+	}
+	for _, warning := range stat.Warnings {
+		fmt.Fprintf(hash, "|%s", warning)
+	}
+	return "\"" + hex.EncodeToString(hash.Sum(nil))[:32] + "\""
+}
+
+func MatchesETag(headers map[string]string, etag string) bool {
+	value := ""
+	for name, header := range headers {
+		if strings.EqualFold(name, "If-None-Match") {
+			value = header
+		}
+	}
+	for _, candidate := range strings.Split(value, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// RespondCacheable answers 304 without body when the client already has the
+// current version of the stat
+func (d *Handler) RespondCacheable(req events.APIGatewayProxyRequest, stat Stat, variant string, body interface{}) (events.APIGatewayProxyResponse, error) {
+	etag := ETag(stat, variant)
+	headers := map[string]string{
+		"ETag": etag,
+	}
+	if d.cacheControl != "" {
+		headers["Cache-Control"] = d.cacheControl
+	}
+	if MatchesETag(req.Headers, etag) {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusNotModified,
+			Headers:    headers,
+		}, nil
+	}
+	response, err := RespondJSON(body)
+	response.Headers = headers
+	return response, err
+}
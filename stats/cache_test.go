@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+func TestStatCache(t *testing.T) {
+	now := time.Date(2026, 10, 19, 5, 0, 0, 0, time.UTC)
+	cache := NewStatCache(time.Minute)
+	cache.Now = func() time.Time { return now }
+	_, ok := cache.Get()
+	if ok {
+		t.Error("No stat expected in an empty cache")
+	}
+	cache.Set(Stat{Ratio: 0.5})
+	stat, ok := cache.Get()
+	if !ok || stat.Ratio != 0.5 {
+		t.Error("Expected the cached stat. Got:", stat)
+	}
+	now = now.Add(time.Minute)
+	_, ok = cache.Get()
+	if ok {
+		t.Error("Expected the stat to expire")
+	}
+}
+
+func TestStatCacheDisabled(t *testing.T) {
+	cache := NewStatCache(0)
+	cache.Set(Stat{Ratio: 0.5})
+	_, ok := cache.Get()
+	if ok {
+		t.Error("No stat expected when the cache is disabled")
+	}
+}
+
+func TestGetStatUsesCache(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	repo.IncrementStat("Mutant")
+	d := Handler{
+		repo:  repo,
+		cache: NewStatCache(time.Minute),
+	}
+	d.GetStat()
+	repo.IncrementStat("Mutant")
+	stat, _ := d.GetStat()
+	if stat.Counts[EnumDnaType.Mutant] != 1 {
+		t.Error("Expected the cached count. Got:", stat.Counts)
+	}
+}
+
+func TestETag(t *testing.T) {
+	stat := Stat{Counts: map[string]int{"Mutant": 1, "Human": 2}}
+	other := Stat{Counts: map[string]int{"Mutant": 2, "Human": 2}}
+	if ETag(stat, "v1") != ETag(stat, "v1") {
+		t.Error("Expected the same etag for the same counts")
+	}
+	if ETag(stat, "v1") == ETag(other, "v1") || ETag(stat, "v1") == ETag(stat, "v2:2") {
+		t.Error("Expected different etags for different counts or variants")
+	}
+}
+
+func TestMatchesETag(t *testing.T) {
+	if !MatchesETag(map[string]string{"if-none-match": "\"a\", W/\"b\""}, "\"b\"") {
+		t.Error("Expected the weak etag to match")
+	}
+	if MatchesETag(map[string]string{}, "\"b\"") {
+		t.Error("No match expected without If-None-Match")
+	}
+}
+
+func TestGetStatsNotModified(t *testing.T) {
+	d := Handler{
+		repo:         repository.NewMemoryRepository(),
+		cacheControl: DEFAULT_CACHE_CONTROL,
+	}
+	response, _ := d.GetStats(events.APIGatewayProxyRequest{})
+	if response.StatusCode != 200 || response.Headers["ETag"] == "" || response.Headers["Cache-Control"] != DEFAULT_CACHE_CONTROL {
+		t.Fatal("Expected the stats with caching headers. Got:", response.Headers)
+	}
+	req := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"If-None-Match": response.Headers["ETag"]},
+	}
+	response, _ = d.GetStats(req)
+	if response.StatusCode != 304 || response.Body != "" {
+		t.Error("304 - Not Modified http status code expected. Got:", response.StatusCode)
+	}
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+type GroupStat struct {
+	Value string `json:"value"`
+	Stat
+}
+
+type Groups struct {
+	GroupBy string      `json:"group_by"`
+	Groups  []GroupStat `json:"groups"`
+}
+
+func IsGroupRequest(params map[string]string) bool {
+	return params["group_by"] != ""
+}
+
+func (d *Handler) GetGroups(params map[string]string) (events.APIGatewayProxyResponse, error) {
+	dimension := params["group_by"]
+	if !repository.IsDimension(dimension) {
+		return RespondError(http.StatusBadRequest)
+	}
+	repo, ok := d.Repository().(repository.DimensionRepository)
+	if !ok {
+		return RespondError(http.StatusNotImplemented)
+	}
+	groups, err := repo.GetGroups(dimension)
+	if err != nil {
+		return RespondError(http.StatusInternalServerError)
+	}
+	response := Groups{
+		GroupBy: dimension,
+		Groups:  []GroupStat{},
+	}
+	for _, g := range groups {
+		var stat Stat
+		stat.SetValues(repository.SortStats(g.Counts))
+		response.Groups = append(response.Groups, GroupStat{
+			Value: g.Value,
+			Stat:  stat,
+		})
+	}
+	return RespondJSON(response)
+}
+
+// MarshalJSON keeps the value, which the one of the embedded stat would drop
+func (g GroupStat) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "{\"value\":%q,", g.Value)
+	err := g.Stat.WriteFields(&buf)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+func (g *GroupStat) UnmarshalJSON(data []byte) error {
+	err := g.Stat.UnmarshalJSON(data)
+	if err != nil {
+		return err
+	}
+	var value struct {
+		Value string `json:"value"`
+	}
+	err = json.Unmarshal(data, &value)
+	g.Value = value.Value
+	return err
+}
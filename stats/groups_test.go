@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+func TestGetGroups(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	repo.RecordDna(repository.DnaData{Uuid: "1", Dna: []string{"AAAA", "CCCC", "TTAT", "AGAA"}, Type: "Mutant"})
+	repo.RecordDna(repository.DnaData{Uuid: "2", Dna: []string{"ATGC", "CAGT", "TTAT", "AGAC"}, Type: "Human"})
+	d := Handler{
+		repo: repo,
+	}
+	req := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"group_by": "size"},
+	}
+	response, _ := d.GetStats(req)
+	if response.StatusCode != 200 {
+		t.Fatal("200 - Ok http status code expected. Got:", response.StatusCode)
+	}
+	groups := Groups{}
+	json.Unmarshal([]byte(response.Body), &groups)
+	if groups.GroupBy != "size" || len(groups.Groups) != 5 {
+		t.Fatal("Expected every size group. Got:", response.Body)
+	}
+	group := groups.Groups[1]
+	if group.Value != "4-6" || group.Counts["Mutant"] != 1 || group.Counts["Human"] != 1 || group.Ratio != 1 {
+		t.Error("Expected a mutant and a human of size 4-6. Got:", response.Body)
+	}
+}
+
+func TestGetGroupsBadRequest(t *testing.T) {
+	d := Handler{
+		repo: repository.NewMemoryRepository(),
+	}
+	req := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{"group_by": "color"},
+	}
+	response, _ := d.GetStats(req)
+	if response.StatusCode != 400 {
+		t.Error("400 - Bad Request http status code expected. Got:", response.StatusCode)
+	}
+}
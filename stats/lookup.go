@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+const DNAS_PATH = "/dnas/"
+
+// IsLookupRequest matches the /dnas/{uuid} resource, by its template behind
+// API Gateway or by its path on the HTTP server
+func IsLookupRequest(req events.APIGatewayProxyRequest) bool {
+	return strings.HasSuffix(req.Resource, "/dnas/{uuid}") || strings.Contains(req.Path, DNAS_PATH)
+}
+
+func LookupUuid(req events.APIGatewayProxyRequest) string {
+	if uuid := req.PathParameters["uuid"]; uuid != "" {
+		return uuid
+	}
+	index := strings.LastIndex(req.Path, DNAS_PATH)
+	if index < 0 {
+		return ""
+	}
+	return strings.Trim(req.Path[index+len(DNAS_PATH):], "/")
+}
+
+// GetDna answers the analysis of a dna by the uuid the detector gave it. It
+// is found once the save lambda stored it
+func (d *Handler) GetDna(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	uuid := LookupUuid(req)
+	if uuid == "" || strings.Contains(uuid, "/") {
+		return RespondError(http.StatusBadRequest)
+	}
+	dna, err := d.Repository().GetDna(uuid)
+	if errors.Is(err, repository.ErrNotFound) {
+		return RespondError(http.StatusNotFound)
+	}
+	if err != nil {
+		return RespondError(http.StatusInternalServerError)
+	}
+	return RespondJSON(dna)
+}
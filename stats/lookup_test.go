@@ -0,0 +1,32 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+func TestLookupUuid(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		Resource:       "/dnas/{uuid}",
+		PathParameters: map[string]string{"uuid": "1"},
+	}
+	if !IsLookupRequest(req) || LookupUuid(req) != "1" {
+		t.Error("Expected the uuid of the path parameter. Got:", LookupUuid(req))
+	}
+	req = events.APIGatewayProxyRequest{Path: "/prod/dnas/2"}
+	if !IsLookupRequest(req) || LookupUuid(req) != "2" {
+		t.Error("Expected the uuid of the path. Got:", LookupUuid(req))
+	}
+}
+
+func TestGetDnaBadRequest(t *testing.T) {
+	d := Handler{
+		repo: repository.NewMemoryRepository(),
+	}
+	response, _ := d.GetStats(events.APIGatewayProxyRequest{Path: "/dnas/"})
+	if response.StatusCode != 400 {
+		t.Error("400 - Bad Request http status code expected. Got:", response.StatusCode)
+	}
+}
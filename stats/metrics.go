@@ -0,0 +1,118 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+const (
+	PROMETHEUS_CONTENT_TYPE  = "text/plain; version=0.0.4; charset=utf-8"
+	OPENMETRICS_CONTENT_TYPE = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// IsMetricsRequest is true for the /stats/metrics resource and for clients
+// that ask for OpenMetrics through the Accept header
+func IsMetricsRequest(req events.APIGatewayProxyRequest) bool {
+	if strings.HasSuffix(req.Resource, "/stats/metrics") || strings.HasSuffix(req.Path, "/stats/metrics") {
+		return true
+	}
+	return IsOpenMetrics(req.Headers)
+}
+
+func IsOpenMetrics(headers map[string]string) bool {
+	for name, value := range headers {
+		if strings.EqualFold(name, "Accept") && strings.Contains(value, "application/openmetrics-text") {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Handler) GetMetrics(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	stat, err := d.GetStat()
+	if err != nil {
+		return RespondError(http.StatusInternalServerError)
+	}
+	buckets := map[string]repository.Bucket{}
+	if repo, ok := d.Repository().(repository.BucketRepository); ok {
+		now := time.Now()
+		for _, granularity := range repository.Granularities {
+			current, err := repo.GetBuckets(granularity, now, now)
+			if err != nil {
+				return RespondError(http.StatusInternalServerError)
+			}
+			if len(current) > 0 {
+				buckets[granularity] = current[0]
+			}
+		}
+	}
+	openMetrics := IsOpenMetrics(req.Headers)
+	contentType := PROMETHEUS_CONTENT_TYPE
+	if openMetrics {
+		contentType = OPENMETRICS_CONTENT_TYPE
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type": contentType,
+		},
+		Body: FormatMetrics(stat, buckets, openMetrics),
+	}, nil
+}
+
+// FormatMetrics renders the stat in the Prometheus text format, or in the
+// OpenMetrics one, which names counters without the _total suffix and ends
+// with # EOF
+func FormatMetrics(stat Stat, buckets map[string]repository.Bucket, openMetrics bool) string {
+	var b strings.Builder
+	family := "magneto_dna_total"
+	if openMetrics {
+		family = "magneto_dna"
+	}
+	fmt.Fprintf(&b, "# HELP %s Number of dnas analyzed by type.\n", family)
+	fmt.Fprintf(&b, "# TYPE %s counter\n", family)
+	for _, dnaType := range RegisteredTypes() {
+		fmt.Fprintf(&b, "magneto_dna_total{type=\"%s\"} %d\n", EscapeLabel(dnaType), stat.Counts[dnaType])
+	}
+	ratio := math.NaN()
+	if human := stat.Counts[EnumDnaType.Human]; human > 0 {
+		ratio = float64(stat.Counts[EnumDnaType.Mutant]) / float64(human)
+	}
+	b.WriteString("# HELP magneto_dna_ratio Ratio of mutant to human dnas, NaN without humans.\n")
+	b.WriteString("# TYPE magneto_dna_ratio gauge\n")
+	fmt.Fprintf(&b, "magneto_dna_ratio %s\n", FormatFloat(ratio))
+	if len(buckets) > 0 {
+		b.WriteString("# HELP magneto_dna_bucket Number of dnas analyzed by type in the current hour and day.\n")
+		b.WriteString("# TYPE magneto_dna_bucket gauge\n")
+		for _, granularity := range repository.Granularities {
+			bucket, ok := buckets[granularity]
+			if !ok {
+				continue
+			}
+			for _, dnaType := range RegisteredTypes() {
+				fmt.Fprintf(&b, "magneto_dna_bucket{granularity=\"%s\",type=\"%s\"} %d\n", granularity, EscapeLabel(dnaType), bucket.Counts[dnaType])
+			}
+		}
+	}
+	if openMetrics {
+		b.WriteString("# EOF\n")
+	}
+	return b.String()
+}
+
+func EscapeLabel(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(value)
+}
+
+func FormatFloat(value float64) string {
+	if math.IsNaN(value) {
+		return "NaN"
+	}
+	return fmt.Sprintf("%g", value)
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+func TestIsMetricsRequest(t *testing.T) {
+	if !IsMetricsRequest(events.APIGatewayProxyRequest{Path: "/stats/metrics"}) {
+		t.Error("Metrics request expected for /stats/metrics")
+	}
+	if !IsMetricsRequest(events.APIGatewayProxyRequest{Headers: map[string]string{"accept": "application/openmetrics-text"}}) {
+		t.Error("Metrics request expected when OpenMetrics is accepted")
+	}
+	if IsMetricsRequest(events.APIGatewayProxyRequest{Path: "/stats"}) {
+		t.Error("No metrics request expected for /stats")
+	}
+}
+
+func TestFormatMetrics(t *testing.T) {
+	stat := Stat{Counts: map[string]int{"Mutant": 1, "Human": 2}}
+	buckets := map[string]repository.Bucket{
+		repository.HOUR: {Counts: map[string]int{"Mutant": 1}},
+	}
+	metrics := FormatMetrics(stat, buckets, false)
+	expected := []string{
+		"# TYPE magneto_dna_total counter\n",
+		"magneto_dna_total{type=\"Mutant\"} 1\n",
+		"magneto_dna_total{type=\"Human\"} 2\n",
+		"magneto_dna_ratio 0.5\n",
+		"magneto_dna_bucket{granularity=\"hour\",type=\"Mutant\"} 1\n",
+	}
+	for _, line := range expected {
+		if !strings.Contains(metrics, line) {
+			t.Error("Expected the metrics to contain", line, "Got:", metrics)
+		}
+	}
+	if strings.Contains(metrics, "# EOF") {
+		t.Error("No EOF expected in the Prometheus format")
+	}
+}
+
+func TestFormatOpenMetrics(t *testing.T) {
+	metrics := FormatMetrics(Stat{}, map[string]repository.Bucket{}, true)
+	if !strings.Contains(metrics, "# TYPE magneto_dna counter\n") || !strings.Contains(metrics, "magneto_dna_ratio NaN\n") || !strings.HasSuffix(metrics, "# EOF\n") {
+		t.Error("Unexpected OpenMetrics:", metrics)
+	}
+}
+
+func TestGetMetrics(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	repo.Now = time.Now
+	repo.IncrementStat("Mutant")
+	d := Handler{
+		repo: repo,
+	}
+	response, _ := d.GetStats(events.APIGatewayProxyRequest{Path: "/stats/metrics"})
+	if response.StatusCode != 200 || response.Headers["Content-Type"] != PROMETHEUS_CONTENT_TYPE {
+		t.Fatal("Expected the Prometheus metrics. Got:", response.StatusCode, response.Headers)
+	}
+	if !strings.Contains(response.Body, "magneto_dna_bucket{granularity=\"day\",type=\"Mutant\"} 1\n") {
+		t.Error("Expected the count of the current day. Got:", response.Body)
+	}
+}
+
+func TestGetMetricsInternalServerError(t *testing.T) {
+	d := Handler{
+		db: &mockDynamoDBClientError{},
+	}
+	response, _ := d.GetStats(events.APIGatewayProxyRequest{Path: "/stats/metrics"})
+	if response.StatusCode != 500 {
+		t.Error("500 http status code expected. Got:", response.StatusCode)
+	}
+}
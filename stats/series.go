@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+type BucketStat struct {
+	Start string `json:"start"`
+	Stat
+}
+
+type Series struct {
+	Granularity string       `json:"granularity"`
+	From        string       `json:"from"`
+	To          string       `json:"to"`
+	Buckets     []BucketStat `json:"buckets"`
+}
+
+func IsSeriesRequest(params map[string]string) bool {
+	return params["from"] != "" || params["to"] != "" || params["granularity"] != ""
+}
+
+func (d *Handler) GetSeries(params map[string]string) (events.APIGatewayProxyResponse, error) {
+	granularity, from, to, err := ParseSeriesRequest(params)
+	if err != nil {
+		return RespondError(http.StatusBadRequest)
+	}
+	repo, ok := d.Repository().(repository.BucketRepository)
+	if !ok {
+		return RespondError(http.StatusNotImplemented)
+	}
+	buckets, err := repo.GetBuckets(granularity, from, to)
+	if err != nil {
+		return RespondError(http.StatusInternalServerError)
+	}
+	series := Series{
+		Granularity: granularity,
+		From:        from.Format(time.RFC3339),
+		To:          to.Format(time.RFC3339),
+		Buckets:     []BucketStat{},
+	}
+	for _, b := range buckets {
+		series.Buckets = append(series.Buckets, NewBucketStat(b))
+	}
+	return RespondJSON(series)
+}
+
+func NewBucketStat(b repository.Bucket) BucketStat {
+	var stat Stat
+	stat.SetValues(repository.SortStats(b.Counts))
+	return BucketStat{
+		Start: b.Start.Format(time.RFC3339),
+		Stat:  stat,
+	}
+}
+
+// MarshalJSON keeps the start, which the one of the embedded stat would drop
+func (b BucketStat) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "{\"start\":%q,", b.Start)
+	err := b.Stat.WriteFields(&buf)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+func (b *BucketStat) UnmarshalJSON(data []byte) error {
+	err := b.Stat.UnmarshalJSON(data)
+	if err != nil {
+		return err
+	}
+	var start struct {
+		Start string `json:"start"`
+	}
+	err = json.Unmarshal(data, &start)
+	b.Start = start.Start
+	return err
+}
+
+// ParseSeriesRequest reads the from and to dates, which are required, and the
+// granularity, which is day by default
+func ParseSeriesRequest(params map[string]string) (string, time.Time, time.Time, error) {
+	granularity := params["granularity"]
+	if granularity == "" {
+		granularity = repository.DAY
+	}
+	if params["from"] == "" || params["to"] == "" {
+		return "", time.Time{}, time.Time{}, errors.New("from and to are required")
+	}
+	from, err := ParseTime(params["from"])
+	if err != nil {
+		return "", time.Time{}, time.Time{}, err
+	}
+	to, err := ParseTime(params["to"])
+	if err != nil {
+		return "", time.Time{}, time.Time{}, err
+	}
+	_, err = repository.BucketStarts(granularity, from, to)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, err
+	}
+	return granularity, from, to, nil
+}
+
+// ParseTime accepts either a RFC3339 timestamp or a date
+func ParseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+func TestIsSeriesRequest(t *testing.T) {
+	if IsSeriesRequest(map[string]string{}) {
+		t.Error("No series request expected without parameters")
+	}
+	if !IsSeriesRequest(map[string]string{"granularity": "hour"}) {
+		t.Error("Series request expected with granularity")
+	}
+}
+
+func TestParseSeriesRequest(t *testing.T) {
+	params := map[string]string{
+		"from": "2026-10-01",
+		"to":   "2026-10-19T05:00:00Z",
+	}
+	granularity, from, to, err := ParseSeriesRequest(params)
+	if err != nil {
+		t.Error("No error expected parsing the series request", err)
+	}
+	if granularity != repository.DAY || from.Day() != 1 || to.Hour() != 5 {
+		t.Error("Unexpected series request:", granularity, from, to)
+	}
+}
+
+func TestParseSeriesRequestWithoutRange(t *testing.T) {
+	_, _, _, err := ParseSeriesRequest(map[string]string{"granularity": "hour"})
+	if err == nil {
+		t.Error("Expected error parsing a series request without range")
+	}
+}
+
+func TestParseSeriesRequestWithInvalidDate(t *testing.T) {
+	_, _, _, err := ParseSeriesRequest(map[string]string{"from": "yesterday", "to": "2026-10-19"})
+	if err == nil {
+		t.Error("Expected error parsing an invalid date")
+	}
+}
+
+func TestGetSeries(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	repo.Now = func() time.Time { return time.Date(2026, 10, 19, 5, 30, 0, 0, time.UTC) }
+	repo.IncrementStat("Mutant")
+	repo.IncrementStat("Human")
+	repo.IncrementStat("Human")
+	d := Handler{
+		repo: repo,
+	}
+	req := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{
+			"from":        "2026-10-18",
+			"to":          "2026-10-19",
+			"granularity": "day",
+		},
+	}
+	response, _ := d.GetStats(req)
+	if response.StatusCode != 200 {
+		t.Fatal("200 - Ok http status code expected. Got:", response.StatusCode)
+	}
+	series := Series{}
+	json.Unmarshal([]byte(response.Body), &series)
+	if len(series.Buckets) != 2 {
+		t.Fatal("Expected 2 buckets. Got:", response.Body)
+	}
+	if series.Buckets[0].Counts["Mutant"] != 0 || series.Buckets[1].Counts["Mutant"] != 1 || series.Buckets[1].Counts["Human"] != 2 || series.Buckets[1].Ratio != 0.5 {
+		t.Error("Unexpected buckets:", response.Body)
+	}
+	if series.Buckets[1].Start != "2026-10-19T00:00:00Z" {
+		t.Error("Expected the bucket to start at midnight. Got:", series.Buckets[1].Start)
+	}
+}
+
+func TestGetSeriesBadRequest(t *testing.T) {
+	d := Handler{
+		repo: repository.NewMemoryRepository(),
+	}
+	req := events.APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{
+			"from":        "2026-10-19",
+			"to":          "2026-10-18",
+			"granularity": "hour",
+		},
+	}
+	response, _ := d.GetStats(req)
+	if response.StatusCode != 400 {
+		t.Error("400 - Bad Request http status code expected. Got:", response.StatusCode)
+	}
+}
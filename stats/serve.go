@@ -0,0 +1,34 @@
+package stats
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/fpinatares/magneto/middleware"
+	"github.com/fpinatares/magneto/router"
+	"github.com/fpinatares/magneto/server"
+)
+
+const DEFAULT_STATS_ADDRESS = ":8081"
+
+func (d *Handler) RunServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	address := server.AddressFlag(flags, DEFAULT_STATS_ADDRESS)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	return server.Serve(*address, d.Routes())
+}
+
+// Routes serves every stats resource with GetStats, which tells them apart by
+// their path
+func (d *Handler) Routes() http.Handler {
+	r := router.New([]server.Route{
+		{Method: http.MethodGet, Path: "/stats", Handler: d.GetStats},
+		{Method: http.MethodGet, Path: "/stats/metrics", Handler: d.GetStats},
+		{Method: http.MethodGet, Path: "/v2/stats", Handler: d.GetStats},
+		{Method: http.MethodGet, Path: "/dnas/{uuid}", Handler: d.GetStats},
+	})
+	return server.Adapt(middleware.Default(r.Handle))
+}
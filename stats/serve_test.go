@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fpinatares/magneto/repository"
+	"github.com/fpinatares/magneto/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestServeStats(t *testing.T) {
+	d := Handler{
+		repo: repository.NewMemoryRepository(),
+	}
+	w := httptest.NewRecorder()
+	d.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	if w.Code != 200 || w.Body.String() != "{\"count_mutant_dna\":0,\"count_human_dna\":0,\"ratio\":0}" {
+		t.Error("Expected the stats. Got:", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	d.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats/metrics", nil))
+	if w.Code != 200 || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Error("Expected the metrics. Got:", w.Code, w.Header())
+	}
+}
+
+func TestServeDna(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	repo.SaveDna(repository.DnaData{Uuid: "1", Dna: []string{"AAAA"}, Type: "Human"})
+	d := Handler{
+		repo: repo,
+	}
+	w := httptest.NewRecorder()
+	d.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dnas/1", nil))
+	if w.Code != 200 || w.Body.String() != "{\"uuid\":\"1\",\"dna\":[\"AAAA\"],\"type\":\"Human\"}" {
+		t.Error("Expected the dna. Got:", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	d.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dnas/2", nil))
+	if w.Code != 404 {
+		t.Error("404 - Not Found http status code expected. Got:", w.Code)
+	}
+}
+
+func TestServeTracesConcurrentRequestsApart(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	routes := NewHandler(tracing.NewDynamoDB(&mockDynamoDBClient{}), nil, nil, "").Routes()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stats", nil))
+		}()
+	}
+	wg.Wait()
+	requests := map[trace.SpanID]trace.TraceID{}
+	reads := []sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		if span.SpanKind() == trace.SpanKindServer {
+			requests[span.SpanContext().SpanID()] = span.SpanContext().TraceID()
+		} else if span.Name() == "DynamoDB.BatchGetItem" {
+			reads = append(reads, span)
+		}
+	}
+	if len(requests) != 20 || len(reads) != 20 {
+		t.Fatal("Expected a span per request and per read of the counters. Got:", len(requests), len(reads))
+	}
+	for _, read := range reads {
+		if requests[read.Parent().SpanID()] != read.SpanContext().TraceID() {
+			t.Error("Expected every read to be a child of its own request. Got:", read.Parent())
+		}
+	}
+}
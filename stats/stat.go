@@ -1,48 +1,94 @@
-package main
+package stats
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
 	"math"
 	"net/http"
+	"os"
+	"strings"
+	"sync"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fpinatares/magneto/detector"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/repository"
+	"github.com/fpinatares/magneto/tracing"
 )
 
+// Stat keeps the count of every registered type, which is rendered as a
+// count_<type>_dna field, and warnings about the types that are not registered
 type Stat struct {
-	Mutant int     `json:"count_mutant_dna"`
-	Human  int     `json:"count_human_dna"`
-	Ratio  float64 `json:"ratio"`
+	Counts   map[string]int
+	Ratio    float64
+	Warnings []string
 }
 
-type StatDB struct {
-	DnaType string `json:"dna_type"`
-	Count   int    `json:"type_count"`
+type StatDB = repository.StatDB
+
+var EnumDnaType = detector.EnumDnaType
+
+var registryMu sync.RWMutex
+
+// registeredTypes are the types reported by the stats, in the order of their
+// fields
+var registeredTypes = []string{EnumDnaType.Mutant, EnumDnaType.Human}
+
+// RegisteredTypes returns a copy of the registered types, which the mains
+// also hand to the repository and the save handler
+func RegisteredTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]string{}, registeredTypes...)
+}
+
+func RegisterDnaType(dnaType string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if dnaType == "" || isRegistered(dnaType) {
+		return
+	}
+	registeredTypes = append(registeredTypes, dnaType)
 }
 
-var EnumDnaType = DnaTypes()
+func IsRegistered(dnaType string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return isRegistered(dnaType)
+}
 
-func DnaTypes() *DnaType {
-	return &DnaType{
-		Human:  "Human",
-		Mutant: "Mutant",
+func isRegistered(dnaType string) bool {
+	for _, registered := range registeredTypes {
+		if registered == dnaType {
+			return true
+		}
 	}
+	return false
 }
 
-type DnaType struct {
-	Human  string
-	Mutant string
+// RegisterDnaTypesFromEnv registers the comma separated types of the DNA_TYPES
+// environment variable
+func RegisterDnaTypesFromEnv() {
+	for _, dnaType := range strings.Split(os.Getenv("DNA_TYPES"), ",") {
+		RegisterDnaType(strings.TrimSpace(dnaType))
+	}
 }
 
-type dependencies struct {
-	db dynamodbiface.DynamoDBAPI
+func CountField(dnaType string) string {
+	return "count_" + strings.ToLower(dnaType) + "_dna"
+}
+
+type Handler struct {
+	db           dynamodbiface.DynamoDBAPI
+	repo         repository.Repository
+	cache        *StatCache
+	cacheControl string
 }
 
 func GetDynamoDBClient() *dynamodb.DynamoDB {
@@ -53,78 +99,165 @@ func GetDynamoDBClient() *dynamodb.DynamoDB {
 	return svc
 }
 
-func main() {
-	svc := GetDynamoDBClient()
-	d := dependencies{
-		db: svc,
+func NewHandler(db dynamodbiface.DynamoDBAPI, repo repository.Repository, cache *StatCache, cacheControl string) *Handler {
+	return &Handler{
+		db:           db,
+		repo:         repo,
+		cache:        cache,
+		cacheControl: cacheControl,
 	}
-	lambda.Start(d.GetStats)
 }
 
-func (d *dependencies) GetStats() (events.APIGatewayProxyResponse, error) {
-	stats, err := d.GetStatsFromDB()
-	if err != nil {
-		return RespondError(http.StatusInternalServerError)
+func (d *Handler) GetStats(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	d = d.WithContext(tracing.Extract(req.Headers))
+	if IsLookupRequest(req) {
+		return d.GetDna(req)
 	}
-
-	var stat Stat
-	err = stat.SetValues(stats)
+	if IsMetricsRequest(req) {
+		return d.GetMetrics(req)
+	}
+	if IsV2Request(req) {
+		return d.GetStatsV2(req)
+	}
+	if IsGroupRequest(req.QueryStringParameters) {
+		return d.GetGroups(req.QueryStringParameters)
+	}
+	if IsSeriesRequest(req.QueryStringParameters) {
+		return d.GetSeries(req.QueryStringParameters)
+	}
+	stat, err := d.GetStat()
 	if err != nil {
 		return RespondError(http.StatusInternalServerError)
 	}
-	return RespondOk(stat)
+	return d.RespondCacheable(req, stat, "v1", stat)
 }
 
-func (d *dependencies) GetStatsFromDB() ([]map[string]*dynamodb.AttributeValue, error) {
-	params := &dynamodb.ScanInput{
-		TableName: aws.String("stats"),
+// WithContext returns a copy of the handler whose DynamoDB calls are traced as
+// children of the span of the context
+func (d *Handler) WithContext(ctx context.Context) *Handler {
+	handler := *d
+	handler.db = tracing.WithContext(d.db, ctx)
+	if d.repo != nil {
+		handler.repo = repository.WithContext(d.repo, ctx)
 	}
-	result, err := d.db.Scan(params)
-	if err != nil {
-		log.Printf("Query API call failed: %s", err)
-		return nil, err
+	return &handler
+}
+
+func (d *Handler) GetStatsFromDB() ([]StatDB, error) {
+	return d.Repository().GetStats()
+}
+
+// Repository defaults to the DynamoDB tables when no other one was configured
+func (d *Handler) Repository() repository.Repository {
+	if d.repo == nil {
+		repo := repository.NewDynamoDBRepository(d.db)
+		repo.DnaTypes = RegisteredTypes()
+		d.repo = repo
 	}
-	return result.Items, nil
+	return d.repo
 }
 
 func (s *Stat) SetCount(dnaType string, count int) error {
-	if dnaType == EnumDnaType.Human {
-		s.Human = count
-	} else if dnaType == EnumDnaType.Mutant {
-		s.Mutant = count
-	} else {
+	if !IsRegistered(dnaType) {
 		return errors.New("DNA Type not supported")
 	}
+	if s.Counts == nil {
+		s.Counts = map[string]int{}
+	}
+	s.Counts[dnaType] = count
 	return nil
 }
 
 func (s *Stat) CalculateRatio() {
-	if s.Human == 0 {
-		s.Ratio = float64(s.Mutant)
+	human := s.Counts[EnumDnaType.Human]
+	mutant := s.Counts[EnumDnaType.Mutant]
+	if human == 0 {
+		s.Ratio = float64(mutant)
 	} else {
-		s.Ratio = math.Round((float64(s.Mutant)/float64(s.Human))*100) / 100
+		s.Ratio = math.Round((float64(mutant)/float64(human))*100) / 100
 	}
 }
 
-func (s *Stat) SetValues(items []map[string]*dynamodb.AttributeValue) error {
-	for _, i := range items {
-		item, err := ParseItem(i)
+// SetValues sets the count of every item, warning about the types that are
+// not registered instead of failing
+func (s *Stat) SetValues(items []StatDB) error {
+	for _, item := range items {
+		err := s.SetCount(item.DnaType, item.Count)
 		if err != nil {
-			return err
+			s.Warn(item.DnaType, item.Count)
 		}
-		s.SetCount(item.DnaType, item.Count)
 	}
 	s.CalculateRatio()
 	return nil
 }
 
-func ParseItem(item map[string]*dynamodb.AttributeValue) (StatDB, error) {
-	stat := StatDB{}
-	err := dynamodbattribute.UnmarshalMap(item, &stat)
+func (s *Stat) Warn(dnaType string, count int) {
+	warning := fmt.Sprintf("Unknown dna type %s with count %d", dnaType, count)
+	logging.Default().Warn("Unknown dna type", "type", dnaType, "count", count)
+	s.Warnings = append(s.Warnings, warning)
+}
+
+func (s Stat) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	err := s.WriteFields(&buf)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// WriteFields writes the fields of the stat without braces, so they can be
+// embedded in other objects
+func (s Stat) WriteFields(buf *bytes.Buffer) error {
+	for _, dnaType := range RegisteredTypes() {
+		fmt.Fprintf(buf, "%q:%d,", CountField(dnaType), s.Counts[dnaType])
+	}
+	ratio, err := json.Marshal(s.Ratio)
 	if err != nil {
-		log.Printf("Got error unmarshalling: %s", err)
+		return err
 	}
-	return stat, err
+	buf.WriteString("\"ratio\":")
+	buf.Write(ratio)
+	if len(s.Warnings) > 0 {
+		warnings, err := json.Marshal(s.Warnings)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(",\"warnings\":")
+		buf.Write(warnings)
+	}
+	return nil
+}
+
+func (s *Stat) UnmarshalJSON(data []byte) error {
+	fields := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+	s.Counts = map[string]int{}
+	for _, dnaType := range RegisteredTypes() {
+		if value, ok := fields[CountField(dnaType)]; ok {
+			var count int
+			err = json.Unmarshal(value, &count)
+			if err != nil {
+				return err
+			}
+			s.Counts[dnaType] = count
+		}
+	}
+	if value, ok := fields["ratio"]; ok {
+		err = json.Unmarshal(value, &s.Ratio)
+		if err != nil {
+			return err
+		}
+	}
+	if value, ok := fields["warnings"]; ok {
+		return json.Unmarshal(value, &s.Warnings)
+	}
+	return nil
 }
 
 func RespondError(status int) (events.APIGatewayProxyResponse, error) {
@@ -135,7 +268,11 @@ func RespondError(status int) (events.APIGatewayProxyResponse, error) {
 }
 
 func RespondOk(stat Stat) (events.APIGatewayProxyResponse, error) {
-	bytes, _ := json.Marshal(stat)
+	return RespondJSON(stat)
+}
+
+func RespondJSON(body interface{}) (events.APIGatewayProxyResponse, error) {
+	bytes, _ := json.Marshal(body)
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
 		Body:       string(bytes),
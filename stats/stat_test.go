@@ -1,12 +1,14 @@
-package main
+package stats
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
 
-	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fpinatares/magneto/repository"
 )
 
 type mockDynamoDBClient struct {
@@ -17,18 +19,25 @@ type mockDynamoDBClientError struct {
 	dynamodbiface.DynamoDBAPI
 }
 
-func (m *mockDynamoDBClient) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
-	return &dynamodb.ScanOutput{}, nil
+func (m *mockDynamoDBClient) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	return &dynamodb.BatchGetItemOutput{}, nil
 }
 
-func (m *mockDynamoDBClientError) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
-	return nil, errors.New("Scan error")
+func (m *mockDynamoDBClientError) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	return nil, errors.New("Batch get item error")
+}
+
+func (m *mockDynamoDBClient) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (m *mockDynamoDBClientError) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return nil, errors.New("Get item error")
 }
 
 func TestSetRatio(t *testing.T) {
 	s := Stat{
-		Mutant: 1,
-		Human:  2,
+		Counts: map[string]int{"Mutant": 1, "Human": 2},
 	}
 	s.CalculateRatio()
 	if s.Ratio != 0.5 {
@@ -38,8 +47,7 @@ func TestSetRatio(t *testing.T) {
 
 func TestSetRatio0Human(t *testing.T) {
 	s := Stat{
-		Mutant: 10,
-		Human:  0,
+		Counts: map[string]int{"Mutant": 10, "Human": 0},
 	}
 	s.CalculateRatio()
 	if s.Ratio != 10 {
@@ -50,16 +58,16 @@ func TestSetRatio0Human(t *testing.T) {
 func TestSetHumanCount(t *testing.T) {
 	s := Stat{}
 	s.SetCount(EnumDnaType.Human, 8)
-	if s.Human != 8 {
-		t.Error("Expected Human count to be 8, got:", s.Human)
+	if s.Counts[EnumDnaType.Human] != 8 {
+		t.Error("Expected Human count to be 8, got:", s.Counts)
 	}
 }
 
 func TestSetMutantCount(t *testing.T) {
 	s := Stat{}
 	s.SetCount(EnumDnaType.Mutant, 10)
-	if s.Mutant != 10 {
-		t.Error("Expected Mutant count to be 10, got:", s.Mutant)
+	if s.Counts[EnumDnaType.Mutant] != 10 {
+		t.Error("Expected Mutant count to be 10, got:", s.Counts)
 	}
 }
 
@@ -72,7 +80,7 @@ func TestUnsupportedDnaTypeCount(t *testing.T) {
 }
 
 func TestGetStatsFromDB(t *testing.T) {
-	d := dependencies{
+	d := Handler{
 		db: &mockDynamoDBClient{},
 	}
 	_, err := d.GetStatsFromDB()
@@ -83,8 +91,7 @@ func TestGetStatsFromDB(t *testing.T) {
 
 func TestRespondOK(t *testing.T) {
 	stat := Stat{
-		Human:  1,
-		Mutant: 2,
+		Counts: map[string]int{"Human": 1, "Mutant": 2},
 		Ratio:  0.5,
 	}
 	response, err := RespondOk(stat)
@@ -100,49 +107,88 @@ func TestRespondError(t *testing.T) {
 	}
 }
 
-func TestParseItem(t *testing.T) {
-	item := make(map[string]*dynamodb.AttributeValue)
-	item["dna_type"] = &dynamodb.AttributeValue{S: aws.String("Human")}
-	item["type_count"] = &dynamodb.AttributeValue{N: aws.String("1")}
-	_, err := ParseItem(item)
+func TestSetValues(t *testing.T) {
+	s := Stat{}
+	items := []StatDB{
+		{DnaType: "Human", Count: 2},
+		{DnaType: "Mutant", Count: 1},
+	}
+	err := s.SetValues(items)
 	if err != nil {
-		t.Error("Error expected while parsing empty item", err)
+		t.Error("No error expected while setting values", err)
+	}
+	if s.Ratio != 0.5 {
+		t.Error("Expected ratio to be 0.5. Got:", s.Ratio)
 	}
 }
 
-func TestSetValues(t *testing.T) {
-	item1 := make(map[string]*dynamodb.AttributeValue)
-	item1["dna_type"] = &dynamodb.AttributeValue{S: aws.String("Human")}
-	item1["type_count"] = &dynamodb.AttributeValue{N: aws.String("2")}
-	item2 := make(map[string]*dynamodb.AttributeValue)
-	item2["dna_type"] = &dynamodb.AttributeValue{S: aws.String("Mutant")}
-	item2["type_count"] = &dynamodb.AttributeValue{N: aws.String("1")}
+func TestSetValuesWithUnknownType(t *testing.T) {
 	s := Stat{}
-	items := []map[string]*dynamodb.AttributeValue{item1, item2}
+	items := []StatDB{
+		{DnaType: "Cyborg", Count: 4},
+		{DnaType: "Mutant", Count: 1},
+	}
 	err := s.SetValues(items)
 	if err != nil {
 		t.Error("No error expected while setting values", err)
 	}
-	if s.Ratio != 0.5 {
-		t.Error("Expected ratio to be 0.5. Got:", s.Ratio)
+	if len(s.Warnings) != 1 || s.Counts[EnumDnaType.Mutant] != 1 {
+		t.Error("Expected a warning about the unknown type. Got:", s.Warnings)
+	}
+}
+
+func TestRegisterDnaType(t *testing.T) {
+	defer func(types []string) { registeredTypes = types }(RegisteredTypes())
+	RegisterDnaType("Cyborg")
+	RegisterDnaType("Cyborg")
+	s := Stat{}
+	s.SetValues([]StatDB{{DnaType: "Cyborg", Count: 4}})
+	bytes, _ := json.Marshal(s)
+	if len(RegisteredTypes()) != 3 || string(bytes) != "{\"count_mutant_dna\":0,\"count_human_dna\":0,\"count_cyborg_dna\":4,\"ratio\":0}" {
+		t.Error("Expected the cyborg count. Got:", string(bytes))
+	}
+}
+
+func TestStatJSON(t *testing.T) {
+	s := Stat{}
+	s.SetValues([]StatDB{{DnaType: "Mutant", Count: 1}, {DnaType: "Cyborg", Count: 4}})
+	bytes, _ := json.Marshal(s)
+	parsed := Stat{}
+	err := json.Unmarshal(bytes, &parsed)
+	if err != nil || parsed.Counts[EnumDnaType.Mutant] != 1 || parsed.Ratio != 1 || len(parsed.Warnings) != 1 {
+		t.Error("Expected the stat to be parsed back. Got:", string(bytes))
+	}
+}
+
+func TestGetStatsFromRepository(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	repo.IncrementStat("Human")
+	repo.IncrementStat("Human")
+	repo.IncrementStat("Mutant")
+	d := Handler{
+		repo: repo,
+	}
+	response, _ := d.GetStats(events.APIGatewayProxyRequest{})
+	if response.StatusCode != 200 || response.Body != "{\"count_mutant_dna\":1,\"count_human_dna\":2,\"ratio\":0.5}" {
+		t.Error("Expected stats from the repository. Got:", response.Body)
 	}
 }
 
 func TestGetStats(t *testing.T) {
-	d := dependencies{
+	d := Handler{
 		db: &mockDynamoDBClient{},
 	}
-	response, _ := d.GetStats()
+	response, _ := d.GetStats(events.APIGatewayProxyRequest{})
 	if response.StatusCode != 200 {
 		t.Error("200 - Ok http status code expected. Got:", response.StatusCode)
 	}
 }
 
 func TestGetStatsInternalServerError(t *testing.T) {
-	d := dependencies{
+	d := Handler{
 		db: &mockDynamoDBClientError{},
 	}
-	response, _ := d.GetStats()
+	response, _ := d.GetStats(events.APIGatewayProxyRequest{})
 	if response.StatusCode != 500 {
 		t.Error("500 http status code expected. Got:", response.StatusCode)
 	}
@@ -0,0 +1,108 @@
+package stats
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	DEFAULT_PRECISION = 2
+	MAX_PRECISION     = 10
+	CONFIDENCE_LEVEL  = 0.95
+	// Z_SCORE is the standard normal quantile of the 95% confidence level
+	Z_SCORE = 1.959963984540054
+)
+
+type ConfidenceInterval struct {
+	Level float64 `json:"level"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// StatV2 leaves undefined metrics as null instead of making up a value
+type StatV2 struct {
+	Counts             map[string]int      `json:"counts"`
+	Total              int                 `json:"total"`
+	Ratio              *float64            `json:"ratio"`
+	MutantShare        *float64            `json:"mutant_share"`
+	ConfidenceInterval *ConfidenceInterval `json:"confidence_interval"`
+	Warnings           []string            `json:"warnings,omitempty"`
+}
+
+func IsV2Request(req events.APIGatewayProxyRequest) bool {
+	return strings.HasSuffix(req.Resource, "/v2/stats") || strings.HasSuffix(req.Path, "/v2/stats")
+}
+
+func (d *Handler) GetStatsV2(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	precision, err := ParsePrecision(req.QueryStringParameters["precision"])
+	if err != nil {
+		return RespondError(http.StatusBadRequest)
+	}
+	stat, err := d.GetStat()
+	if err != nil {
+		return RespondError(http.StatusInternalServerError)
+	}
+	return d.RespondCacheable(req, stat, "v2:"+strconv.Itoa(precision), NewStatV2(stat, precision))
+}
+
+func ParsePrecision(value string) (int, error) {
+	if value == "" {
+		return DEFAULT_PRECISION, nil
+	}
+	precision, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if precision < 0 || precision > MAX_PRECISION {
+		return 0, errors.New("precision out of range")
+	}
+	return precision, nil
+}
+
+func NewStatV2(stat Stat, precision int) StatV2 {
+	v2 := StatV2{
+		Counts:   map[string]int{},
+		Warnings: stat.Warnings,
+	}
+	for _, dnaType := range RegisteredTypes() {
+		v2.Counts[strings.ToLower(dnaType)] = stat.Counts[dnaType]
+		v2.Total += stat.Counts[dnaType]
+	}
+	mutant := stat.Counts[EnumDnaType.Mutant]
+	human := stat.Counts[EnumDnaType.Human]
+	if human > 0 {
+		v2.Ratio = Round(float64(mutant)/float64(human), precision)
+	}
+	if v2.Total > 0 {
+		v2.MutantShare = Round(float64(mutant)/float64(v2.Total), precision)
+		lower, upper := WilsonInterval(mutant, v2.Total, Z_SCORE)
+		v2.ConfidenceInterval = &ConfidenceInterval{
+			Level: CONFIDENCE_LEVEL,
+			Lower: *Round(lower, precision),
+			Upper: *Round(upper, precision),
+		}
+	}
+	return v2
+}
+
+// WilsonInterval is the Wilson score interval of the proportion of successes
+// out of total, which unlike the normal approximation stays within [0, 1]
+func WilsonInterval(successes int, total int, z float64) (float64, float64) {
+	n := float64(total)
+	p := float64(successes) / n
+	denominator := 1 + z*z/n
+	center := (p + z*z/(2*n)) / denominator
+	margin := z * math.Sqrt(p*(1-p)/n+z*z/(4*n*n)) / denominator
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
+
+func Round(value float64, precision int) *float64 {
+	scale := math.Pow(10, float64(precision))
+	rounded := math.Round(value*scale) / scale
+	return &rounded
+}
@@ -0,0 +1,101 @@
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+func TestIsV2Request(t *testing.T) {
+	if IsV2Request(events.APIGatewayProxyRequest{Path: "/stats"}) {
+		t.Error("No v2 request expected for /stats")
+	}
+	if !IsV2Request(events.APIGatewayProxyRequest{Resource: "/v2/stats"}) {
+		t.Error("V2 request expected for /v2/stats")
+	}
+}
+
+func TestParsePrecision(t *testing.T) {
+	precision, err := ParsePrecision("")
+	if err != nil || precision != DEFAULT_PRECISION {
+		t.Error("Expected the default precision. Got:", precision)
+	}
+	_, err = ParsePrecision("11")
+	if err == nil {
+		t.Error("Expected error parsing a precision out of range")
+	}
+	_, err = ParsePrecision("two")
+	if err == nil {
+		t.Error("Expected error parsing an invalid precision")
+	}
+}
+
+func TestWilsonInterval(t *testing.T) {
+	lower, upper := WilsonInterval(1, 3, Z_SCORE)
+	if math.Abs(lower-0.0615) > 0.001 || math.Abs(upper-0.7923) > 0.0001 {
+		t.Error("Unexpected interval:", lower, upper)
+	}
+	lower, upper = WilsonInterval(0, 10, Z_SCORE)
+	if lower != 0 || upper <= 0 {
+		t.Error("Expected the interval to start at 0. Got:", lower, upper)
+	}
+}
+
+func TestNewStatV2WithoutHumans(t *testing.T) {
+	stat := Stat{Counts: map[string]int{"Mutant": 4}}
+	v2 := NewStatV2(stat, 2)
+	if v2.Ratio != nil {
+		t.Error("Expected an undefined ratio. Got:", *v2.Ratio)
+	}
+	if v2.Total != 4 || *v2.MutantShare != 1 {
+		t.Error("Expected every dna to be mutant. Got:", v2)
+	}
+}
+
+func TestNewStatV2WithoutDnas(t *testing.T) {
+	v2 := NewStatV2(Stat{}, 2)
+	bytes, _ := json.Marshal(v2)
+	if string(bytes) != "{\"counts\":{\"human\":0,\"mutant\":0},\"total\":0,\"ratio\":null,\"mutant_share\":null,\"confidence_interval\":null}" {
+		t.Error("Expected null metrics. Got:", string(bytes))
+	}
+}
+
+func TestGetStatsV2(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	repo.IncrementStat("Human")
+	repo.IncrementStat("Human")
+	repo.IncrementStat("Mutant")
+	d := Handler{
+		repo: repo,
+	}
+	req := events.APIGatewayProxyRequest{
+		Resource:              "/v2/stats",
+		QueryStringParameters: map[string]string{"precision": "3"},
+	}
+	response, _ := d.GetStats(req)
+	if response.StatusCode != 200 {
+		t.Fatal("200 - Ok http status code expected. Got:", response.StatusCode)
+	}
+	v2 := StatV2{}
+	json.Unmarshal([]byte(response.Body), &v2)
+	if v2.Total != 3 || *v2.Ratio != 0.5 || *v2.MutantShare != 0.333 || v2.ConfidenceInterval.Lower != 0.061 {
+		t.Error("Unexpected stats:", response.Body)
+	}
+}
+
+func TestGetStatsV2BadRequest(t *testing.T) {
+	d := Handler{
+		repo: repository.NewMemoryRepository(),
+	}
+	req := events.APIGatewayProxyRequest{
+		Resource:              "/v2/stats",
+		QueryStringParameters: map[string]string{"precision": "-1"},
+	}
+	response, _ := d.GetStats(req)
+	if response.StatusCode != 400 {
+		t.Error("400 - Bad Request http status code expected. Got:", response.StatusCode)
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// PermanentError is a failure that would happen again on every delivery of
+// the same message, so retrying it is pointless
+type PermanentError struct {
+	Reason string
+	Err    error
+}
+
+func (e *PermanentError) Error() string {
+	if e.Err == nil {
+		return e.Reason
+	}
+	return e.Reason + ": " + e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+func NewPermanentError(reason string, err error) error {
+	return &PermanentError{
+		Reason: reason,
+		Err:    err,
+	}
+}
+
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// IsRetryable reports whether the message should be redelivered. Throttling,
+// transaction conflicts and 5xx responses are retried, as well as any error
+// that can not be classified, like network failures
+func IsRetryable(err error) bool {
+	if err == nil || IsPermanent(err) {
+		return false
+	}
+	if canceled, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		return !IsValidationCancellation(canceled)
+	}
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case dynamodb.ErrCodeProvisionedThroughputExceededException,
+			dynamodb.ErrCodeRequestLimitExceeded,
+			dynamodb.ErrCodeTransactionConflictException,
+			dynamodb.ErrCodeInternalServerError,
+			"ThrottlingException":
+			return true
+		}
+	}
+	if rerr, ok := err.(awserr.RequestFailure); ok {
+		return rerr.StatusCode() >= http.StatusInternalServerError
+	}
+	return true
+}
+
+func IsValidationCancellation(canceled *dynamodb.TransactionCanceledException) bool {
+	for _, reason := range canceled.CancellationReasons {
+		if aws.StringValue(reason.Code) == "ValidationError" {
+			return true
+		}
+	}
+	return false
+}
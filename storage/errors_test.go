@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func NewRetryableTestError() error {
+	return awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throttled", nil)
+}
+
+func TestPermanentErrorIsNotRetryable(t *testing.T) {
+	err := NewPermanentError("malformed message", errors.New("unexpected end of JSON input"))
+	if IsRetryable(err) {
+		t.Error("Permanent error should not be retryable")
+	}
+	if err.Error() != "malformed message: unexpected end of JSON input" {
+		t.Error("Unexpected error message. Got:", err.Error())
+	}
+}
+
+func TestThrottlingIsRetryable(t *testing.T) {
+	if !IsRetryable(NewRetryableTestError()) {
+		t.Error("Throttling error should be retryable")
+	}
+}
+
+func TestServerErrorIsRetryable(t *testing.T) {
+	err := awserr.NewRequestFailure(awserr.New("ServiceUnavailable", "unavailable", nil), 503, "")
+	if !IsRetryable(err) {
+		t.Error("5xx error should be retryable")
+	}
+}
+
+func TestClientErrorIsNotRetryable(t *testing.T) {
+	err := awserr.NewRequestFailure(awserr.New("ValidationException", "item too large", nil), 400, "")
+	if IsRetryable(err) {
+		t.Error("4xx error should not be retryable")
+	}
+}
+
+func TestUnknownErrorIsRetryable(t *testing.T) {
+	if !IsRetryable(errors.New("connection reset")) {
+		t.Error("Unknown error should be retryable")
+	}
+}
+
+func TestTransactionValidationIsNotRetryable(t *testing.T) {
+	err := &dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("ValidationError")},
+		},
+	}
+	if IsRetryable(err) {
+		t.Error("Transaction cancelled by validation should not be retryable")
+	}
+}
+
+func TestTransactionConflictIsRetryable(t *testing.T) {
+	err := &dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("TransactionConflict")},
+		},
+	}
+	if !IsRetryable(err) {
+		t.Error("Transaction cancelled by conflict should be retryable")
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/fpinatares/magneto/repository"
+)
+
+const PROCESSED_MESSAGES_TABLE = repository.PROCESSED_MESSAGES_TABLE
+const PROCESSED_MESSAGE_TTL = repository.PROCESSED_MESSAGE_TTL
+
+type ProcessedMessage = repository.ProcessedMessage
+
+// UpdateDataOnce skips the messages that were already processed, so a
+// redelivered message is acknowledged without saving or counting it again.
+// The lookup only spares the transaction to the usual redelivery: two
+// concurrent deliveries are told apart by RecordMessage, which marks the
+// message in the same transaction that counts the dna
+func (d *Handler) UpdateDataOnce(messageId string, dnaData DnaData) error {
+	if messageId == "" {
+		return d.UpdateData(dnaData)
+	}
+	processed, err := d.IsProcessed(messageId)
+	if err != nil {
+		return err
+	}
+	if processed {
+		d.Logger().Info("Skipped message already processed")
+		return nil
+	}
+	if recorder, ok := d.Repository().(repository.MessageRecorder); ok && !d.StatsFromStream {
+		return d.RecordMessage(recorder, messageId, dnaData)
+	}
+	err = d.UpdateData(dnaData)
+	if err != nil {
+		return err
+	}
+	return d.MarkProcessed(messageId)
+}
+
+func (d *Handler) RecordMessage(recorder repository.MessageRecorder, messageId string, dnaData DnaData) error {
+	err := recorder.RecordMessage(messageId, dnaData)
+	switch {
+	case errors.Is(err, repository.ErrAlreadyProcessed):
+		d.Logger().Info("Skipped message already processed")
+		return nil
+	case errors.Is(err, repository.ErrDuplicateDna):
+		d.Logger().Info("Skipped dna already saved")
+		return nil
+	}
+	return err
+}
+
+func (d *Handler) IsProcessed(messageId string) (bool, error) {
+	return d.Messages().IsProcessed(messageId)
+}
+
+func (d *Handler) MarkProcessed(messageId string) error {
+	return d.Messages().MarkProcessed(messageId)
+}
+
+// Messages keeps the processed and quarantined messages in the repository, so
+// the save path needs no other backend than the one of the dnas
+func (d *Handler) Messages() repository.MessageStore {
+	if store, ok := d.Repository().(repository.MessageStore); ok {
+		return store
+	}
+	return repository.NewDynamoDBRepository(d.db)
+}
@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fpinatares/magneto/repository"
+	"github.com/fpinatares/magneto/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func (m *mockDynamoDBClient) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (m *mockDynamoDBClientError) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return nil, errors.New("Get item error")
+}
+
+type mockDynamoDBClientProcessed struct {
+	dynamodbiface.DynamoDBAPI
+	writes int
+}
+
+func (m *mockDynamoDBClientProcessed) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{
+		Item: map[string]*dynamodb.AttributeValue{
+			"message_id": input.Key["message_id"],
+			"expires_at": {N: aws.String("1")},
+		},
+	}, nil
+}
+
+func (m *mockDynamoDBClientProcessed) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	m.writes++
+	return nil, nil
+}
+
+func (m *mockDynamoDBClientProcessed) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.writes++
+	return nil, nil
+}
+
+// mockDynamoDBClientRace has not seen the message yet, but another delivery
+// of it commits first
+type mockDynamoDBClientRace struct {
+	mockDynamoDBClient
+	input *dynamodb.TransactWriteItemsInput
+}
+
+func (m *mockDynamoDBClientRace) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.input = input
+	return nil, &dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed")},
+		},
+	}
+}
+
+func TestIsProcessed(t *testing.T) {
+	d := Handler{
+		db: &mockDynamoDBClientProcessed{},
+	}
+	processed, err := d.IsProcessed("message-1")
+	if err != nil || !processed {
+		t.Error("Expected message to be processed", err)
+	}
+}
+
+func TestIsNotProcessed(t *testing.T) {
+	d := Handler{
+		db: &mockDynamoDBClient{},
+	}
+	processed, err := d.IsProcessed("message-1")
+	if err != nil || processed {
+		t.Error("Expected message not to be processed", err)
+	}
+}
+
+func TestErrorOnIsProcessed(t *testing.T) {
+	d := Handler{
+		db: &mockDynamoDBClientError{},
+	}
+	_, err := d.IsProcessed("message-1")
+	if err == nil {
+		t.Error("Expected error while checking processed message")
+	}
+}
+
+func TestMarkProcessed(t *testing.T) {
+	d := Handler{
+		db: &mockDynamoDBClient{},
+	}
+	err := d.MarkProcessed("message-1")
+	if err != nil {
+		t.Error("No error expected marking message as processed", err)
+	}
+}
+
+func TestUpdateDataOnce(t *testing.T) {
+	dnaData := new(DnaData)
+	dnaData.Type = EnumDnaType.Human
+	dnaData.Uuid = uuid.New().String()
+	d := Handler{
+		db: &mockDynamoDBClient{},
+	}
+	err := d.UpdateDataOnce("message-1", *dnaData)
+	if err != nil {
+		t.Error("No error expected updating data", err)
+	}
+}
+
+func TestUpdateDataOnceSkipsProcessedMessage(t *testing.T) {
+	dnaData := new(DnaData)
+	dnaData.Type = EnumDnaType.Human
+	dnaData.Uuid = uuid.New().String()
+	db := &mockDynamoDBClientProcessed{}
+	d := Handler{
+		db: db,
+	}
+	err := d.UpdateDataOnce("message-1", *dnaData)
+	if err != nil {
+		t.Error("No error expected updating a processed message", err)
+	}
+	if db.writes != 0 {
+		t.Error("No writes expected for a processed message. Got:", db.writes)
+	}
+}
+
+func TestUpdateDataOnceSkipsConcurrentDelivery(t *testing.T) {
+	dnaData := new(DnaData)
+	dnaData.Type = EnumDnaType.Human
+	dnaData.Uuid = uuid.New().String()
+	db := &mockDynamoDBClientRace{}
+	d := Handler{
+		db: db,
+	}
+	err := d.UpdateDataOnce("message-1", *dnaData)
+	if err != nil {
+		t.Error("No error expected when another delivery got there first", err)
+	}
+	if db.input == nil || db.input.TransactItems[1].Put == nil || *db.input.TransactItems[1].Put.TableName != PROCESSED_MESSAGES_TABLE {
+		t.Error("Expected the message to be marked in the transaction of the dna. Got:", db.input)
+	}
+}
+
+func TestSaveRedeliveredMessage(t *testing.T) {
+	var record events.SNSEventRecord
+	record.SNS.MessageID = "message-1"
+	record.SNS.Message = "{\"uuid\":\"098765yh-876h-98j7-0o9i-987654tyh65t\",\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"],\"type\":\"Human\"}"
+	records := []events.SNSEventRecord{record}
+
+	event := events.SNSEvent{
+		Records: records,
+	}
+	db := &mockDynamoDBClientProcessed{}
+	d := Handler{
+		db: db,
+	}
+	err := d.Save(event)
+	if err != nil {
+		t.Error("No error expected", err)
+	}
+	if db.writes != 0 {
+		t.Error("No writes expected for a redelivered message. Got:", db.writes)
+	}
+}
+
+func TestSaveContinuesTheTraceOfTheDetector(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	var record events.SNSEventRecord
+	record.SNS.MessageID = "message-1"
+	record.SNS.Message = "{\"uuid\":\"098765yh-876h-98j7-0o9i-987654tyh65t\",\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"],\"type\":\"Human\"}"
+	record.SNS.MessageAttributes = map[string]interface{}{
+		"traceparent": map[string]interface{}{
+			"Type":  "String",
+			"Value": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+	}
+	d := Handler{
+		db: tracing.NewDynamoDB(&mockDynamoDBClientProcessed{}),
+	}
+	err := d.Save(events.SNSEvent{Records: []events.SNSEventRecord{record}})
+	if err != nil {
+		t.Error("No error expected", err)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatal("Expected the spans of the lookup and the processing. Got:", len(spans))
+	}
+	lookup, process := spans[0], spans[1]
+	if process.Name() != "save-dna process" || process.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Error("Expected the processing to continue the published span. Got:", process.Name(), process.Parent())
+	}
+	if lookup.Name() != "DynamoDB.GetItem" || lookup.Parent().SpanID() != process.SpanContext().SpanID() {
+		t.Error("Expected the lookup as a child of the processing. Got:", lookup.Name(), lookup.Parent())
+	}
+}
+
+func TestSubmittedAt(t *testing.T) {
+	submittedAt, ok := SubmittedAt("{\"uuid\":\"1\",\"submitted_at\":\"2021-01-02T03:04:05.5Z\"}")
+	if !ok || submittedAt.Nanosecond() != 500000000 {
+		t.Error("Expected when the dna was submitted. Got:", submittedAt, ok)
+	}
+	_, ok = SubmittedAt("{\"uuid\":\"1\"}")
+	if ok {
+		t.Error("Expected no submission time for an older message")
+	}
+}
+
+func TestSaveWithoutDynamoDB(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	d := Handler{
+		repo: repo,
+	}
+	var record events.SNSEventRecord
+	record.SNS.MessageID = "message-1"
+	record.SNS.Message = "{\"uuid\":\"1\",\"dna\":[\"ATGC\"],\"type\":\"Human\"}"
+	event := events.SNSEvent{Records: []events.SNSEventRecord{record}}
+	d.Save(event)
+	record.SNS.Message = "{\"uuid\":\"2\",\"dna\":[\"ATGC\"],\"type\":\"Human\"}"
+	d.Save(events.SNSEvent{Records: []events.SNSEventRecord{record}})
+	stats, _ := repo.GetStats()
+	if len(stats) != 1 || stats[0].Count != 1 {
+		t.Error("Expected the redelivered message to be skipped. Got:", stats)
+	}
+	record.SNS.MessageID = "message-2"
+	record.SNS.Message = "{"
+	err := d.Save(events.SNSEvent{Records: []events.SNSEventRecord{record}})
+	quarantined, _ := repo.GetQuarantined("message-2")
+	if err != nil || len(quarantined) != 1 {
+		t.Error("Expected the malformed message to be quarantined in the repository. Got:", quarantined, err)
+	}
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fpinatares/magneto/repository"
+)
+
+const QUARANTINE_TABLE = repository.QUARANTINE_TABLE
+
+type QuarantinedMessage = repository.QuarantinedMessage
+
+// HandleError decides what happens with a message that failed. Retryable
+// errors are returned so the message is redelivered, while permanent ones are
+// quarantined and acknowledged
+func (d *Handler) HandleError(messageId string, message string, err error) error {
+	if err == nil || IsRetryable(err) {
+		return err
+	}
+	d.MessageLogger(messageId, message).Warn("Quarantining message", "error", err)
+	qerr := d.Quarantine(messageId, message, err.Error())
+	if qerr != nil {
+		return err
+	}
+	return nil
+}
+
+// Quarantine keeps the message under its id. A record without one, which the
+// key of the table cannot hold, is kept under a key derived from its content
+func (d *Handler) Quarantine(messageId string, message string, reason string) error {
+	if messageId == "" {
+		messageId = QuarantineKey(message)
+	}
+	return d.Messages().Quarantine(QuarantinedMessage{
+		MessageId:     messageId,
+		Message:       message,
+		Reason:        reason,
+		QuarantinedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// QuarantineKey is the same for every delivery of a message, so a redelivered
+// record without id replaces its entry instead of adding another
+func QuarantineKey(message string) string {
+	sum := sha256.Sum256([]byte(message))
+	return "sha256-" + hex.EncodeToString(sum[:])
+}
+
+func (d *Handler) GetQuarantinedMessages(messageId string) ([]QuarantinedMessage, error) {
+	return d.Messages().GetQuarantined(messageId)
+}
+
+func (d *Handler) RemoveFromQuarantine(messageId string) error {
+	return d.Messages().RemoveFromQuarantine(messageId)
+}
+
+// Replay feeds the quarantined messages through the save path again. The ones
+// that succeed are removed from quarantine and the ones that still fail keep
+// their entry with the new reason. A message whose new reason cannot be
+// written is reported as failed with both errors
+func (d *Handler) Replay(messageId string, out io.Writer) error {
+	messages, err := d.GetQuarantinedMessages(messageId)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, m := range messages {
+		err = d.ProcessMessage(m.MessageId, m.Message)
+		if err == nil {
+			err = d.RemoveFromQuarantine(m.MessageId)
+		} else if !IsRetryable(err) {
+			qerr := d.Quarantine(m.MessageId, m.Message, err.Error())
+			if qerr != nil {
+				err = fmt.Errorf("%s, and quarantining it again failed: %s", err, qerr)
+			}
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "%s\tfailed\t%s\n", m.MessageId, err)
+			continue
+		}
+		fmt.Fprintf(out, "%s\treplayed\n", m.MessageId)
+	}
+	fmt.Fprintf(out, "%d replayed, %d failed\n", len(messages)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d messages could not be replayed", failed)
+	}
+	return nil
+}
+
+func (d *Handler) RunReplay(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	messageId := flags.String("message-id", "", "replay only the quarantined message with this id")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	return d.Replay(*messageId, out)
+}
@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+type mockDynamoDBClientQuarantine struct {
+	dynamodbiface.DynamoDBAPI
+	items       []map[string]*dynamodb.AttributeValue
+	quarantined []string
+	removed     []string
+}
+
+func (m *mockDynamoDBClientQuarantine) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	if *input.TableName == QUARANTINE_TABLE && len(m.items) > 0 {
+		return &dynamodb.GetItemOutput{Item: m.items[0]}, nil
+	}
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (m *mockDynamoDBClientQuarantine) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	if *input.TableName == QUARANTINE_TABLE {
+		key := input.Item["message_id"]
+		if key == nil || key.S == nil || *key.S == "" {
+			return nil, errors.New("ValidationException: missing the key message_id")
+		}
+		m.quarantined = append(m.quarantined, *key.S)
+	}
+	return nil, nil
+}
+
+func (m *mockDynamoDBClientQuarantine) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return nil, nil
+}
+
+func (m *mockDynamoDBClientQuarantine) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	fn(&dynamodb.ScanOutput{Items: m.items}, true)
+	return nil
+}
+
+func (m *mockDynamoDBClientQuarantine) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	m.removed = append(m.removed, *input.Key["message_id"].S)
+	return nil, nil
+}
+
+type mockDynamoDBClientQuarantineError struct {
+	mockDynamoDBClientQuarantine
+}
+
+func (m *mockDynamoDBClientQuarantineError) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return nil, errors.New("quarantine table unavailable")
+}
+
+func CreateQuarantinedItem(messageId string, message string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"message_id": {S: aws.String(messageId)},
+		"message":    {S: aws.String(message)},
+		"reason":     {S: aws.String("malformed message")},
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	db := &mockDynamoDBClientQuarantine{}
+	d := Handler{
+		db: db,
+	}
+	err := d.Quarantine("message-1", "", "malformed message")
+	if err != nil {
+		t.Error("No error expected quarantining a message", err)
+	}
+	if len(db.quarantined) != 1 {
+		t.Error("Expected message to be quarantined")
+	}
+}
+
+func TestErrorOnQuarantine(t *testing.T) {
+	d := Handler{
+		db: &mockDynamoDBClientError{},
+	}
+	err := d.Quarantine("message-1", "", "malformed message")
+	if err == nil {
+		t.Error("Expected error quarantining a message")
+	}
+}
+
+func TestHandleRetryableError(t *testing.T) {
+	db := &mockDynamoDBClientQuarantine{}
+	d := Handler{
+		db: db,
+	}
+	err := d.HandleError("message-1", "", NewRetryableTestError())
+	if err == nil {
+		t.Error("Expected retryable error to be returned")
+	}
+	if len(db.quarantined) != 0 {
+		t.Error("No quarantined message expected for a retryable error")
+	}
+}
+
+func TestHandlePermanentError(t *testing.T) {
+	db := &mockDynamoDBClientQuarantine{}
+	d := Handler{
+		db: db,
+	}
+	err := d.HandleError("message-1", "", NewPermanentError("malformed message", nil))
+	if err != nil {
+		t.Error("No error expected after quarantining a permanent error", err)
+	}
+	if len(db.quarantined) != 1 {
+		t.Error("Expected message to be quarantined")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	message := "{\"uuid\":\"098765yh-876h-98j7-0o9i-987654tyh65t\",\"dna\":[\"ATGCGA\"],\"type\":\"Human\"}"
+	db := &mockDynamoDBClientQuarantine{
+		items: []map[string]*dynamodb.AttributeValue{CreateQuarantinedItem("message-1", message)},
+	}
+	d := Handler{
+		db: db,
+	}
+	var out bytes.Buffer
+	err := d.RunReplay([]string{}, &out)
+	if err != nil {
+		t.Error("No error expected replaying messages", err)
+	}
+	if len(db.removed) != 1 || db.removed[0] != "message-1" {
+		t.Error("Expected message-1 to be removed from quarantine. Got:", db.removed)
+	}
+}
+
+func TestReplaySingleMessage(t *testing.T) {
+	message := "{\"uuid\":\"098765yh-876h-98j7-0o9i-987654tyh65t\",\"dna\":[\"ATGCGA\"],\"type\":\"Mutant\"}"
+	db := &mockDynamoDBClientQuarantine{
+		items: []map[string]*dynamodb.AttributeValue{CreateQuarantinedItem("message-1", message)},
+	}
+	d := Handler{
+		db: db,
+	}
+	var out bytes.Buffer
+	err := d.RunReplay([]string{"-message-id", "message-1"}, &out)
+	if err != nil {
+		t.Error("No error expected replaying a message", err)
+	}
+	if len(db.removed) != 1 {
+		t.Error("Expected message to be removed from quarantine")
+	}
+}
+
+func TestReplayStillFailingMessage(t *testing.T) {
+	db := &mockDynamoDBClientQuarantine{
+		items: []map[string]*dynamodb.AttributeValue{CreateQuarantinedItem("message-1", "")},
+	}
+	d := Handler{
+		db: db,
+	}
+	var out bytes.Buffer
+	err := d.RunReplay([]string{}, &out)
+	if err == nil {
+		t.Error("Expected error replaying a malformed message")
+	}
+	if len(db.removed) != 0 || len(db.quarantined) != 1 {
+		t.Error("Expected message to stay in quarantine")
+	}
+}
+
+func TestReplayReportsFailingQuarantine(t *testing.T) {
+	db := &mockDynamoDBClientQuarantineError{}
+	db.items = []map[string]*dynamodb.AttributeValue{CreateQuarantinedItem("message-1", "")}
+	d := Handler{
+		db: db,
+	}
+	var out bytes.Buffer
+	err := d.RunReplay([]string{}, &out)
+	if err == nil {
+		t.Error("Expected error replaying a malformed message")
+	}
+	if !strings.Contains(out.String(), "quarantining it again failed: quarantine table unavailable") {
+		t.Error("Expected the report to show the quarantine error. Got:", out.String())
+	}
+}
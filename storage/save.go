@@ -1,69 +1,166 @@
-package main
+package storage
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fpinatares/magneto/detector"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/repository"
+	"github.com/fpinatares/magneto/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const NECESSARY_SECUENCE = 4
-const NECESSARY_SECUENCES = 2
-const STATS_TABLE = "stats"
-const DNAS_TABLE = "dnas"
+const SQS_EVENT_SOURCE = "sqs"
+const STREAM_STATS_SOURCE = "stream"
 
-var EnumDnaType = DnaTypes()
+var EnumDnaType = detector.EnumDnaType
 
-func DnaTypes() *DnaType {
-	return &DnaType{
-		Human:  "Human",
-		Mutant: "Mutant",
-	}
+type DnaData = detector.DnaData
+
+type Handler struct {
+	db   dynamodbiface.DynamoDBAPI
+	repo repository.Repository
+	// StatsFromStream leaves the counters to the streams lambda
+	StatsFromStream bool
+	// DnaTypes are the types the save accepts, Human and Mutant when empty
+	DnaTypes []string
+	logger   *logging.Logger
 }
 
-type DnaType struct {
-	Human  string
-	Mutant string
+func NewHandler(db dynamodbiface.DynamoDBAPI, repo repository.Repository) *Handler {
+	return &Handler{
+		db:   db,
+		repo: repo,
+	}
 }
 
-type DnaData struct {
-	Uuid string   `json:"uuid"`
-	Dna  []string `json:"dna"`
-	Type string   `json:"type"`
+// WithLogger returns a copy of the handler whose entries are logged with the
+// logger of the invocation or request
+func (d *Handler) WithLogger(logger *logging.Logger) *Handler {
+	handler := *d
+	handler.logger = logger
+	return &handler
 }
 
-type dependencies struct {
-	db dynamodbiface.DynamoDBAPI
+// WithContext returns a copy of the handler whose DynamoDB calls are traced as
+// children of the span of the context
+func (d *Handler) WithContext(ctx context.Context) *Handler {
+	handler := *d
+	handler.db = tracing.WithContext(d.db, ctx)
+	if d.repo != nil {
+		handler.repo = repository.WithContext(d.repo, ctx)
+	}
+	return &handler
 }
 
-func main() {
-	svc := GetDynamoDBClient()
-	d := dependencies{
-		db: svc,
+func (d *Handler) Logger() *logging.Logger {
+	if d.logger == nil {
+		return logging.Default()
 	}
+	return d.logger
+}
+
+// MessageLogger is the logger of the handler with the ids of the message
+func (d *Handler) MessageLogger(messageId string, message string) *logging.Logger {
+	return d.Logger().With(logging.CORRELATION_ID, CorrelationId(message), "message_id", messageId)
+}
+
+func (d *Handler) Save(event events.SNSEvent) error {
+	record := event.Records[0].SNS
+	return d.Process(tracing.ExtractEvent(record.MessageAttributes), record.MessageID, record.Message)
+}
 
-	lambda.Start(d.Save)
+// Process saves the message within a consumer span that continues the trace
+// of the detector, and is the parent of the spans of the DynamoDB calls. The
+// span has how long the dna took from its submission to the stats, or to its
+// saving when the stats are left to the streams lambda
+func (d *Handler) Process(ctx context.Context, messageId string, message string) error {
+	ctx, span := tracing.Tracer().Start(ctx, "save-dna process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "aws_sns"),
+			attribute.String("messaging.message.id", messageId),
+		),
+	)
+	d = d.WithContext(ctx)
+	err := d.ProcessMessage(messageId, message)
+	if err == nil {
+		if submittedAt, ok := SubmittedAt(message); ok {
+			key := "magneto.submission_to_stats_ms"
+			if d.StatsFromStream {
+				key = "magneto.submission_to_save_ms"
+			}
+			span.SetAttributes(attribute.Float64(key, float64(time.Since(submittedAt))/float64(time.Millisecond)))
+		}
+	}
+	err = d.HandleError(messageId, message, err)
+	tracing.End(span, err)
+	return err
 }
 
-func (d *dependencies) Save(event events.SNSEvent) error {
-	dnaData, err := ParseRequest(event.Records[0].SNS.Message)
+func (d *Handler) ProcessMessage(messageId string, message string) error {
+	dnaData, err := ParseRequest(message)
 	if err != nil {
-		return err
+		return NewPermanentError("malformed message", err)
+	}
+	d = d.WithLogger(d.MessageLogger(messageId, message).With("uuid", dnaData.Uuid))
+	err = ValidateType(dnaData.Type, d.DnaTypes)
+	if err == nil {
+		err = d.UpdateDataOnce(messageId, dnaData)
 	}
-	err = d.UpdateData(dnaData)
 	if err != nil {
+		d.Logger().Error("Got error saving dna", "error", err)
 		return err
 	}
+	d.Logger().Info("Saved dna", "type", dnaData.Type)
 	return nil
 }
 
+// CorrelationId is the one the detector published with the dna, which is
+// empty for the messages of older detectors
+func CorrelationId(message string) string {
+	body := struct {
+		CorrelationId string `json:"correlation_id"`
+	}{}
+	json.Unmarshal([]byte(message), &body)
+	return body.CorrelationId
+}
+
+// SubmittedAt is when the dna of the message was submitted to the detector,
+// which older detectors do not publish
+func SubmittedAt(message string) (time.Time, bool) {
+	body := struct {
+		SubmittedAt string `json:"submitted_at"`
+	}{}
+	json.Unmarshal([]byte(message), &body)
+	submittedAt, err := time.Parse(time.RFC3339Nano, body.SubmittedAt)
+	return submittedAt, err == nil
+}
+
+// ValidateType accepts the given types, or Human and Mutant when there are
+// none. The save main gives it the types the stats report
+func ValidateType(dnaType string, types []string) error {
+	if len(types) == 0 {
+		types = []string{EnumDnaType.Mutant, EnumDnaType.Human}
+	}
+	for _, accepted := range types {
+		if accepted == dnaType {
+			return nil
+		}
+	}
+	return NewPermanentError("unknown dna type "+dnaType, nil)
+}
+
 func ParseRequest(body string) (DnaData, error) {
 	dnaData := new(DnaData)
 	err := json.Unmarshal([]byte(body), &dnaData)
@@ -74,60 +171,32 @@ func ParseRequest(body string) (DnaData, error) {
 	return *dnaData, nil
 }
 
-func (d *dependencies) UpdateData(dnaData DnaData) error {
-	err := d.SaveDna(dnaData)
-	if err != nil {
-		return err
+func (d *Handler) UpdateData(dnaData DnaData) error {
+	if d.StatsFromStream {
+		return d.SaveDna(dnaData)
+	}
+	err := repository.Record(d.Repository(), dnaData)
+	if errors.Is(err, repository.ErrDuplicateDna) {
+		d.Logger().Info("Skipped dna already saved")
+		return nil
 	}
-	err = d.UpdateStats(dnaData.Type)
 	return err
 }
 
-func (d *dependencies) UpdateStats(dnaType string) error {
-	input := CreateUpdateItemInput(dnaType)
-	_, err := d.db.UpdateItem(input)
-	if err != nil {
-		log.Printf("Got error calling UpdateItem: %s", err)
-		return err
-	}
-	return nil
+func (d *Handler) UpdateStats(dnaType string) error {
+	return d.Repository().IncrementStat(dnaType)
 }
 
-func CreateUpdateItemInput(dnaType string) *dynamodb.UpdateItemInput {
-	input := &dynamodb.UpdateItemInput{
-		TableName: aws.String(STATS_TABLE),
-		Key: map[string]*dynamodb.AttributeValue{
-			"dna_type": {
-				S: aws.String(dnaType),
-			},
-		},
-		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":inc": {
-				N: aws.String("1"),
-			},
-		},
-		UpdateExpression: aws.String("ADD type_count :inc"),
-	}
-	return input
+func (d *Handler) SaveDna(dnaData DnaData) error {
+	return d.Repository().SaveDna(dnaData)
 }
 
-func (d *dependencies) SaveDna(dnaData DnaData) error {
-	av, err := dynamodbattribute.MarshalMap(dnaData)
-	if err != nil {
-		log.Printf("Got error calling MarshalMap: %s", err)
-		return err
-	}
-	tableName := DNAS_TABLE
-	input := &dynamodb.PutItemInput{
-		Item:      av,
-		TableName: aws.String(tableName),
+// Repository defaults to the DynamoDB tables when no other one was configured
+func (d *Handler) Repository() repository.Repository {
+	if d.repo == nil {
+		d.repo = repository.NewDynamoDBRepository(d.db)
 	}
-	_, err = d.db.PutItem(input)
-	if err != nil {
-		log.Printf("Got error calling PutItem: %s", err)
-		return err
-	}
-	return nil
+	return d.repo
 }
 
 func GetDynamoDBClient() *dynamodb.DynamoDB {
@@ -1,12 +1,17 @@
-package main
+package storage
 
 import (
+	"bytes"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/repository"
 	"github.com/google/uuid"
 )
 
@@ -34,8 +39,29 @@ func (m *mockDynamoDBClientError) PutItem(input *dynamodb.PutItemInput) (*dynamo
 	return nil, errors.New("Put item error")
 }
 
+func (m *mockDynamoDBClient) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return nil, nil
+}
+
+func (m *mockDynamoDBClientError) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return nil, errors.New("Transact write items error")
+}
+
+type mockDynamoDBClientDuplicate struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m *mockDynamoDBClientDuplicate) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return nil, &dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed")},
+			{Code: aws.String("None")},
+		},
+	}
+}
+
 func TestUpdateStats(t *testing.T) {
-	d := dependencies{
+	d := Handler{
 		db: &mockDynamoDBClient{},
 	}
 	err := d.UpdateStats(EnumDnaType.Mutant)
@@ -45,7 +71,7 @@ func TestUpdateStats(t *testing.T) {
 }
 
 func TestErrorOnUpdateStats(t *testing.T) {
-	d := dependencies{
+	d := Handler{
 		db: &mockDynamoDBClientError{},
 	}
 	err := d.UpdateStats("")
@@ -58,7 +84,7 @@ func TestSaveDna(t *testing.T) {
 	dnaData := new(DnaData)
 	dnaData.Uuid = uuid.New().String()
 	dnaData.Type = EnumDnaType.Mutant
-	d := dependencies{
+	d := Handler{
 		db: &mockDynamoDBClient{},
 	}
 	err := d.SaveDna(*dnaData)
@@ -72,7 +98,7 @@ func TestUpdateData(t *testing.T) {
 	dnaData.Dna = []string{"AAXAAA", "XXXXXX", "XXXXXX", "XXXXXX", "XXXXXX", "XXXXXX"}
 	dnaData.Type = EnumDnaType.Human
 	dnaData.Uuid = uuid.New().String()
-	d := dependencies{
+	d := Handler{
 		db: &mockDynamoDBClient{},
 	}
 	err := d.UpdateData(*dnaData)
@@ -81,6 +107,69 @@ func TestUpdateData(t *testing.T) {
 	}
 }
 
+func TestUpdateDataWithStatsFromStream(t *testing.T) {
+	dnaData := new(DnaData)
+	dnaData.Type = EnumDnaType.Human
+	dnaData.Uuid = uuid.New().String()
+	repo := repository.NewMemoryRepository()
+	d := Handler{
+		repo:            repo,
+		StatsFromStream: true,
+	}
+	err := d.UpdateData(*dnaData)
+	if err != nil {
+		t.Error("No error expected updating data", err)
+	}
+	stats, _ := repo.GetStats()
+	if len(stats) != 0 {
+		t.Error("Expected the counters to be left to the stream. Got:", stats)
+	}
+	_, err = repo.GetDna(dnaData.Uuid)
+	if err != nil {
+		t.Error("Expected the dna to be saved", err)
+	}
+}
+
+func TestErrorOnUpdateData(t *testing.T) {
+	dnaData := new(DnaData)
+	dnaData.Type = EnumDnaType.Human
+	dnaData.Uuid = uuid.New().String()
+	d := Handler{
+		db: &mockDynamoDBClientError{},
+	}
+	err := d.UpdateData(*dnaData)
+	if err == nil {
+		t.Error("Expected error while updating data")
+	}
+}
+
+func TestUpdateDataWithDuplicateDna(t *testing.T) {
+	dnaData := new(DnaData)
+	dnaData.Type = EnumDnaType.Human
+	dnaData.Uuid = uuid.New().String()
+	d := Handler{
+		db: &mockDynamoDBClientDuplicate{},
+	}
+	err := d.UpdateData(*dnaData)
+	if err != nil {
+		t.Error("No error expected updating a duplicate dna", err)
+	}
+}
+
+func TestCorrelationId(t *testing.T) {
+	correlationId := CorrelationId("{\"uuid\":\"1\",\"correlation_id\":\"abc\"}")
+	if correlationId != "abc" {
+		t.Error("Expected the correlation id of the message. Got:", correlationId)
+	}
+}
+
+func TestCorrelationIdOfOlderMessage(t *testing.T) {
+	correlationId := CorrelationId("{\"uuid\":\"1\"}")
+	if correlationId != "" {
+		t.Error("Expected no correlation id. Got:", correlationId)
+	}
+}
+
 func TestErrorParsingEmptyRequest(t *testing.T) {
 	body := ""
 	_, err := ParseRequest(body)
@@ -112,7 +201,7 @@ func TestSave(t *testing.T) {
 	event := events.SNSEvent{
 		Records: records,
 	}
-	d := dependencies{
+	d := Handler{
 		db: &mockDynamoDBClient{},
 	}
 	err := d.Save(event)
@@ -121,6 +210,25 @@ func TestSave(t *testing.T) {
 	}
 }
 
+func TestSaveLogsWithTheLoggerOfTheInvocation(t *testing.T) {
+	var record events.SNSEventRecord
+	record.SNS.Message = "{\"uuid\":\"1\",\"dna\":[\"ATGC\"],\"type\":\"Human\"}"
+	out := &bytes.Buffer{}
+	d := &Handler{
+		repo: repository.NewMemoryRepository(),
+	}
+	err := d.WithLogger(logging.New(out, logging.INFO).WithRequestId("request-1")).Save(events.SNSEvent{Records: []events.SNSEventRecord{record}})
+	if err != nil {
+		t.Error("No error expected", err)
+	}
+	if !strings.Contains(out.String(), `"msg":"Saved dna","request_id":"request-1"`) {
+		t.Error("Expected the entry to have the id of the invocation. Got:", out.String())
+	}
+	if d.logger != nil {
+		t.Error("Expected the logger of the invocation not to outlive it")
+	}
+}
+
 func TestErrorUpdatingItemOnSave(t *testing.T) {
 	var record events.SNSEventRecord
 	record.SNS.Message = "{\"uuid\":\"098765yh-876h-98j7-0o9i-987654tyh65t\",\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"],\"type\":\"Human\"}"
@@ -129,7 +237,7 @@ func TestErrorUpdatingItemOnSave(t *testing.T) {
 	event := events.SNSEvent{
 		Records: records,
 	}
-	d := dependencies{
+	d := Handler{
 		db: &mockDynamoDBClientError{},
 	}
 	err := d.Save(event)
@@ -146,11 +254,74 @@ func TestErrorUpdatingEmptyItemOnSave(t *testing.T) {
 	event := events.SNSEvent{
 		Records: records,
 	}
-	d := dependencies{
-		db: &mockDynamoDBClient{},
+	db := &mockDynamoDBClientQuarantine{}
+	d := Handler{
+		db: db,
+	}
+	err := d.Save(event)
+	if err != nil {
+		t.Error("No error expected after quarantining an empty request", err)
+	}
+	if len(db.quarantined) != 1 {
+		t.Error("Expected the empty request to be quarantined")
+	} else if db.quarantined[0] != QuarantineKey("") {
+		t.Error("Expected the empty request under the key of its content. Got:", db.quarantined[0])
+	}
+}
+
+func TestErrorQuarantiningEmptyItemOnSave(t *testing.T) {
+	var record events.SNSEventRecord
+	record.SNS.Message = ""
+	records := []events.SNSEventRecord{record}
+
+	event := events.SNSEvent{
+		Records: records,
+	}
+	d := Handler{
+		db: &mockDynamoDBClientError{},
 	}
 	err := d.Save(event)
 	if err == nil {
-		t.Error("Expected error parsing an empty request")
+		t.Error("Expected error when the empty request can not be quarantined")
+	}
+}
+
+func TestSaveUnknownDnaType(t *testing.T) {
+	var record events.SNSEventRecord
+	record.SNS.Message = "{\"uuid\":\"098765yh-876h-98j7-0o9i-987654tyh65t\",\"dna\":[\"ATGCGA\"],\"type\":\"Cyborg\"}"
+	records := []events.SNSEventRecord{record}
+
+	event := events.SNSEvent{
+		Records: records,
+	}
+	db := &mockDynamoDBClientQuarantine{}
+	d := Handler{
+		db: db,
+	}
+	err := d.Save(event)
+	if err != nil {
+		t.Error("No error expected after quarantining an unknown dna type", err)
+	}
+	if len(db.quarantined) != 1 {
+		t.Error("Expected the unknown dna type to be quarantined")
+	}
+}
+
+func TestValidateType(t *testing.T) {
+	if ValidateType(EnumDnaType.Mutant, nil) != nil || ValidateType(EnumDnaType.Human, nil) != nil {
+		t.Error("No error expected validating known dna types")
+	}
+	if !IsPermanent(ValidateType("Cyborg", nil)) {
+		t.Error("Expected permanent error validating an unknown dna type")
+	}
+}
+
+func TestValidateGivenType(t *testing.T) {
+	types := []string{EnumDnaType.Mutant, EnumDnaType.Human, "Cyborg"}
+	if ValidateType("Cyborg", types) != nil {
+		t.Error("No error expected validating a given dna type")
+	}
+	if !IsPermanent(ValidateType("Robot", types)) {
+		t.Error("Expected permanent error validating a type that was not given")
 	}
 }
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/middleware"
+	"github.com/fpinatares/magneto/router"
+	"github.com/fpinatares/magneto/server"
+	"github.com/fpinatares/magneto/tracing"
+)
+
+const DEFAULT_SAVE_ADDRESS = ":8082"
+
+func (d *Handler) RunServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	address := server.AddressFlag(flags, DEFAULT_SAVE_ADDRESS)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	return server.Serve(*address, d.Routes())
+}
+
+func (d *Handler) Routes() http.Handler {
+	r := router.New([]server.Route{
+		{Method: http.MethodPost, Path: "/save", Handler: d.SaveDirect},
+	})
+	return server.Adapt(middleware.Default(r.Handle))
+}
+
+// SaveDirect saves the dna of the body, which is the message the detector
+// publishes. The caller gets the error instead of it being quarantined, and
+// the X-Message-Id header, when sent, makes retries idempotent
+func (d *Handler) SaveDirect(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	d = d.WithContext(tracing.Extract(req.Headers)).WithLogger(middleware.Logger(req))
+	err := d.ProcessMessage(middleware.Header(req, "X-Message-Id"), req.Body)
+	if err != nil {
+		if IsPermanent(err) {
+			return Respond(http.StatusBadRequest)
+		}
+		return Respond(http.StatusInternalServerError)
+	}
+	return Respond(http.StatusOK)
+}
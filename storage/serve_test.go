@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/repository"
+)
+
+func TestServeSave(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	d := Handler{
+		db:   &mockDynamoDBClient{},
+		repo: repo,
+	}
+	w := httptest.NewRecorder()
+	body := "{\"uuid\":\"1\",\"dna\":[\"ATGC\"],\"type\":\"Human\"}"
+	d.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/save", strings.NewReader(body)))
+	if w.Code != 200 {
+		t.Error("200 - Ok http status code expected. Got:", w.Code)
+	}
+	_, err := repo.GetDna("1")
+	if err != nil {
+		t.Error("Expected the dna to be saved", err)
+	}
+}
+
+func TestServeSaveMalformed(t *testing.T) {
+	d := Handler{
+		db:   &mockDynamoDBClient{},
+		repo: repository.NewMemoryRepository(),
+	}
+	w := httptest.NewRecorder()
+	d.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/save", strings.NewReader("{")))
+	if w.Code != 400 {
+		t.Error("400 - Bad Request http status code expected. Got:", w.Code)
+	}
+}
+
+func TestServeSaveError(t *testing.T) {
+	d := Handler{
+		db: &mockDynamoDBClientError{},
+	}
+	w := httptest.NewRecorder()
+	body := "{\"uuid\":\"1\",\"dna\":[\"ATGC\"],\"type\":\"Human\"}"
+	d.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/save", strings.NewReader(body)))
+	if w.Code != 500 {
+		t.Error("500 http status code expected. Got:", w.Code)
+	}
+}
+
+func TestServeSaveWithMessageIdWithoutDynamoDB(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	d := Handler{
+		repo: repo,
+	}
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		body := "{\"uuid\":\"" + strconv.Itoa(i) + "\",\"dna\":[\"ATGC\"],\"type\":\"Human\"}"
+		req := httptest.NewRequest(http.MethodPost, "/save", strings.NewReader(body))
+		req.Header.Set("X-Message-Id", "message-1")
+		d.Routes().ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Error("200 - Ok http status code expected. Got:", w.Code)
+		}
+	}
+	stats, _ := repo.GetStats()
+	if len(stats) != 1 || stats[0].Count != 1 {
+		t.Error("Expected the retried message to be counted once. Got:", stats)
+	}
+}
+
+func TestSaveDirectWithLowerCaseMessageId(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	d := &Handler{
+		repo: repo,
+	}
+	for i := 0; i < 2; i++ {
+		req := events.APIGatewayProxyRequest{
+			Headers: map[string]string{"x-message-id": "message-1"},
+			Body:    "{\"uuid\":\"" + strconv.Itoa(i) + "\",\"dna\":[\"ATGC\"],\"type\":\"Human\"}",
+		}
+		response, _ := d.SaveDirect(req)
+		if response.StatusCode != 200 {
+			t.Error("200 - Ok http status code expected. Got:", response.StatusCode)
+		}
+	}
+	stats, _ := repo.GetStats()
+	if len(stats) != 1 || stats[0].Count != 1 {
+		t.Error("Expected the lower case message id to make the retry idempotent. Got:", stats)
+	}
+}
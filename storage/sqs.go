@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/fpinatares/magneto/tracing"
+)
+
+func (d *Handler) SaveBatch(event events.SQSEvent) (events.SQSEventResponse, error) {
+	failures := []events.SQSBatchItemFailure{}
+	for _, message := range event.Records {
+		messageId, body := UnwrapMessage(message)
+		err := d.Process(MessageContext(message), messageId, body)
+		if err != nil {
+			d.MessageLogger(messageId, body).Error("Got error saving message", "error", err, "queue_message_id", message.MessageId)
+			failures = append(failures, events.SQSBatchItemFailure{
+				ItemIdentifier: message.MessageId,
+			})
+		}
+	}
+	return events.SQSEventResponse{
+		BatchItemFailures: failures,
+	}, nil
+}
+
+func GetMessageBody(body string) string {
+	_, body = UnwrapMessage(events.SQSMessage{Body: body})
+	return body
+}
+
+// MessageContext is the trace context in the attributes of the notification,
+// which are in the body without raw message delivery and are the attributes
+// of the queue message with it
+func MessageContext(message events.SQSMessage) context.Context {
+	entity := events.SNSEntity{}
+	err := json.Unmarshal([]byte(message.Body), &entity)
+	if err != nil || entity.Type != "Notification" {
+		return tracing.ExtractSQS(message.MessageAttributes)
+	}
+	return tracing.ExtractEvent(entity.MessageAttributes)
+}
+
+// UnwrapMessage returns the SNS message id and body when the queue
+// subscription does not have raw message delivery enabled, so the same
+// notification keeps its id across deliveries
+func UnwrapMessage(message events.SQSMessage) (string, string) {
+	entity := events.SNSEntity{}
+	err := json.Unmarshal([]byte(message.Body), &entity)
+	if err != nil || entity.Type != "Notification" {
+		return message.MessageId, message.Body
+	}
+	return entity.MessageID, entity.Message
+}
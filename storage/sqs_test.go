@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestSaveBatch(t *testing.T) {
+	var message events.SQSMessage
+	message.MessageId = "message-1"
+	message.Body = "{\"uuid\":\"098765yh-876h-98j7-0o9i-987654tyh65t\",\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"],\"type\":\"Human\"}"
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{message},
+	}
+	d := Handler{
+		db: &mockDynamoDBClient{},
+	}
+	response, err := d.SaveBatch(event)
+	if err != nil {
+		t.Error("No error expected", err)
+	}
+	if len(response.BatchItemFailures) != 0 {
+		t.Error("No batch item failures expected. Got:", len(response.BatchItemFailures))
+	}
+}
+
+func TestSaveBatchQuarantinesMalformedMessages(t *testing.T) {
+	var valid events.SQSMessage
+	valid.MessageId = "message-1"
+	valid.Body = "{\"uuid\":\"098765yh-876h-98j7-0o9i-987654tyh65t\",\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"],\"type\":\"Human\"}"
+	var malformed events.SQSMessage
+	malformed.MessageId = "message-2"
+	malformed.Body = ""
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{valid, malformed},
+	}
+	db := &mockDynamoDBClientQuarantine{}
+	d := Handler{
+		db: db,
+	}
+	response, _ := d.SaveBatch(event)
+	if len(response.BatchItemFailures) != 0 {
+		t.Error("No batch item failures expected. Got:", response.BatchItemFailures)
+	}
+	if len(db.quarantined) != 1 || db.quarantined[0] != "message-2" {
+		t.Error("Expected only message-2 to be quarantined. Got:", db.quarantined)
+	}
+}
+
+func TestSaveBatchReportsDatabaseErrors(t *testing.T) {
+	var message events.SQSMessage
+	message.MessageId = "message-1"
+	message.Body = "{\"uuid\":\"098765yh-876h-98j7-0o9i-987654tyh65t\",\"dna\":[\"ATGCGA\",\"CAGTGC\",\"TTATTT\",\"AGACGG\",\"GCGTCA\",\"TCACTG\"],\"type\":\"Human\"}"
+	event := events.SQSEvent{
+		Records: []events.SQSMessage{message},
+	}
+	d := Handler{
+		db: &mockDynamoDBClientError{},
+	}
+	response, _ := d.SaveBatch(event)
+	if len(response.BatchItemFailures) != 1 {
+		t.Error("Expected one batch item failure. Got:", len(response.BatchItemFailures))
+	}
+}
+
+func TestGetMessageBodyFromSNSEnvelope(t *testing.T) {
+	body := "{\"Type\":\"Notification\",\"Message\":\"{\\\"uuid\\\":\\\"1\\\"}\"}"
+	if GetMessageBody(body) != "{\"uuid\":\"1\"}" {
+		t.Error("Expected the SNS message to be unwrapped. Got:", GetMessageBody(body))
+	}
+}
+
+func TestGetMessageBodyFromRawMessage(t *testing.T) {
+	body := "{\"uuid\":\"1\"}"
+	if GetMessageBody(body) != body {
+		t.Error("Expected the raw message to be returned. Got:", GetMessageBody(body))
+	}
+}
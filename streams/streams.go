@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/fpinatares/magneto/detector"
+	"github.com/fpinatares/magneto/logging"
+	"github.com/fpinatares/magneto/repository"
+)
+
+type DnaData = detector.DnaData
+
+type dependencies struct {
+	repo   *repository.DynamoDBRepository
+	logger *logging.Logger
+}
+
+// WithLogger returns a copy of the dependencies whose entries are logged with
+// the logger of the invocation
+func (d *dependencies) WithLogger(logger *logging.Logger) *dependencies {
+	dependencies := *d
+	dependencies.logger = logger
+	return &dependencies
+}
+
+func (d *dependencies) Logger() *logging.Logger {
+	if d.logger == nil {
+		return logging.Default()
+	}
+	return d.logger
+}
+
+func main() {
+	logging.Setup()
+	svc := GetDynamoDBClient()
+	repo := repository.NewDynamoDBRepository(svc)
+	config := repository.ConfigFromEnv()
+	repo.StatShards = config.StatShards
+	if config.StatsTable != "" {
+		repo.StatsTable = config.StatsTable
+	}
+	err := repo.RecordShards()
+	if err != nil {
+		log.Fatal(err)
+	}
+	d := dependencies{
+		repo: repo,
+	}
+	lambda.Start(func(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+		invocation := d.WithLogger(logging.StartInvocation(ctx))
+		response, err := invocation.HandleStream(event)
+		invocation.Logger().Info("Handled stream records", "records", len(event.Records), "failures", len(response.BatchItemFailures))
+		return response, err
+	})
+}
+
+// HandleStream applies the records in order. When one fails, it is reported
+// with the rest of the batch unprocessed, so they are retried after it
+func (d *dependencies) HandleStream(event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	response := events.DynamoDBEventResponse{
+		BatchItemFailures: []events.DynamoDBBatchItemFailure{},
+	}
+	for _, record := range event.Records {
+		err := d.ProcessRecord(record)
+		if err != nil {
+			d.Logger().Error("Got error processing record", "event_id", record.EventID, "error", err)
+			response.BatchItemFailures = append(response.BatchItemFailures, events.DynamoDBBatchItemFailure{
+				ItemIdentifier: record.Change.SequenceNumber,
+			})
+			return response, nil
+		}
+	}
+	return response, nil
+}
+
+func (d *dependencies) ProcessRecord(record events.DynamoDBEventRecord) error {
+	changes, err := GetChanges(record)
+	if err != nil {
+		return err
+	}
+	err = d.repo.ApplyChanges(record.EventID, changes)
+	if errors.Is(err, repository.ErrAlreadyApplied) {
+		d.Logger().Info("Skipped record already applied", "event_id", record.EventID)
+		return nil
+	}
+	return err
+}
+
+// GetChanges counts inserted dnas and discounts removed ones, which includes
+// the ones expired by TTL. A modified dna is moved between counters only when
+// its type or sequences changed
+func GetChanges(record events.DynamoDBEventRecord) ([]repository.Change, error) {
+	switch events.DynamoDBOperationType(record.EventName) {
+	case events.DynamoDBOperationTypeInsert:
+		dna, err := ParseImage(record.Change.NewImage)
+		if err != nil {
+			return nil, err
+		}
+		return []repository.Change{{Dna: dna, Delta: 1, Analyzed: true}}, nil
+	case events.DynamoDBOperationTypeRemove:
+		dna, err := ParseImage(record.Change.OldImage)
+		if err != nil {
+			return nil, err
+		}
+		return []repository.Change{{Dna: dna, Delta: -1}}, nil
+	case events.DynamoDBOperationTypeModify:
+		old, err := ParseImage(record.Change.OldImage)
+		if err != nil {
+			return nil, err
+		}
+		dna, err := ParseImage(record.Change.NewImage)
+		if err != nil {
+			return nil, err
+		}
+		if old.Type == dna.Type && SameSequences(old.Dna, dna.Dna) {
+			return nil, nil
+		}
+		return []repository.Change{{Dna: old, Delta: -1}, {Dna: dna, Delta: 1}}, nil
+	}
+	return nil, errors.New("unknown event " + record.EventName)
+}
+
+// ParseImage reads the dna of a stream image, which needs the NEW_AND_OLD_IMAGES
+// view type
+func ParseImage(image map[string]events.DynamoDBAttributeValue) (DnaData, error) {
+	dna := DnaData{}
+	if image == nil {
+		return dna, errors.New("missing image, the stream must include new and old images")
+	}
+	dnaType, ok := image["type"]
+	if !ok || dnaType.DataType() != events.DataTypeString {
+		return dna, errors.New("missing type")
+	}
+	dna.Type = dnaType.String()
+	if uuid, ok := image["uuid"]; ok && uuid.DataType() == events.DataTypeString {
+		dna.Uuid = uuid.String()
+	}
+	if sequences, ok := image["dna"]; ok && sequences.DataType() == events.DataTypeList {
+		for i, sequence := range sequences.List() {
+			if sequence.DataType() != events.DataTypeString {
+				return dna, errors.New("invalid sequence " + strconv.Itoa(i))
+			}
+			dna.Dna = append(dna.Dna, sequence.String())
+		}
+	}
+	return dna, nil
+}
+
+func SameSequences(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func GetDynamoDBClient() *dynamodb.DynamoDB {
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	svc := dynamodb.New(sess)
+	return svc
+}
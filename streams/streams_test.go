@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/fpinatares/magneto/repository"
+)
+
+type mockDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	transactions []*dynamodb.TransactWriteItemsInput
+}
+
+type mockDynamoDBClientError struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m *mockDynamoDBClient) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.transactions = append(m.transactions, input)
+	return nil, nil
+}
+
+func (m *mockDynamoDBClientError) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return nil, errors.New("Transact write items error")
+}
+
+func NewImage(dnaType string, sequences ...string) map[string]events.DynamoDBAttributeValue {
+	dna := []events.DynamoDBAttributeValue{}
+	for _, sequence := range sequences {
+		dna = append(dna, events.NewStringAttribute(sequence))
+	}
+	return map[string]events.DynamoDBAttributeValue{
+		"uuid": events.NewStringAttribute("1"),
+		"type": events.NewStringAttribute(dnaType),
+		"dna":  events.NewListAttribute(dna),
+	}
+}
+
+func TestGetChangesOnInsert(t *testing.T) {
+	record := events.DynamoDBEventRecord{
+		EventName: "INSERT",
+		Change:    events.DynamoDBStreamRecord{NewImage: NewImage("Mutant", "AAAA")},
+	}
+	changes, err := GetChanges(record)
+	if err != nil || len(changes) != 1 || changes[0].Delta != 1 || !changes[0].Analyzed || changes[0].Dna.Dna[0] != "AAAA" {
+		t.Error("Expected the mutant to be counted. Got:", changes, err)
+	}
+}
+
+func TestGetChangesOnRemove(t *testing.T) {
+	record := events.DynamoDBEventRecord{
+		EventName: "REMOVE",
+		Change:    events.DynamoDBStreamRecord{OldImage: NewImage("Human")},
+	}
+	changes, err := GetChanges(record)
+	if err != nil || len(changes) != 1 || changes[0].Delta != -1 || changes[0].Dna.Type != "Human" {
+		t.Error("Expected the human to be discounted. Got:", changes, err)
+	}
+}
+
+func TestGetChangesOnModify(t *testing.T) {
+	record := events.DynamoDBEventRecord{
+		EventName: "MODIFY",
+		Change: events.DynamoDBStreamRecord{
+			OldImage: NewImage("Human", "ATGC"),
+			NewImage: NewImage("Human", "ATGC"),
+		},
+	}
+	changes, _ := GetChanges(record)
+	if len(changes) != 0 {
+		t.Error("No changes expected when the dna is the same. Got:", changes)
+	}
+	record.Change.NewImage = NewImage("Mutant", "ATGC")
+	changes, _ = GetChanges(record)
+	if len(changes) != 2 || changes[0].Dna.Type != "Human" || changes[1].Dna.Type != "Mutant" {
+		t.Error("Expected the dna to move from human to mutant. Got:", changes)
+	}
+}
+
+func TestGetChangesWithoutImage(t *testing.T) {
+	_, err := GetChanges(events.DynamoDBEventRecord{EventName: "INSERT"})
+	if err == nil {
+		t.Error("Expected error without the new image")
+	}
+}
+
+func TestHandleStream(t *testing.T) {
+	db := &mockDynamoDBClient{}
+	d := dependencies{
+		repo: repository.NewDynamoDBRepository(db),
+	}
+	event := events.DynamoDBEvent{
+		Records: []events.DynamoDBEventRecord{
+			{EventID: "1", EventName: "INSERT", Change: events.DynamoDBStreamRecord{NewImage: NewImage("Mutant")}},
+			{EventID: "2", EventName: "REMOVE", Change: events.DynamoDBStreamRecord{OldImage: NewImage("Mutant")}},
+		},
+	}
+	response, err := d.HandleStream(event)
+	if err != nil || len(response.BatchItemFailures) != 0 {
+		t.Error("No failures expected. Got:", response.BatchItemFailures)
+	}
+	if len(db.transactions) != 2 {
+		t.Error("Expected a transaction per record. Got:", len(db.transactions))
+	}
+}
+
+func TestHandleStreamStopsOnFailure(t *testing.T) {
+	d := dependencies{
+		repo: repository.NewDynamoDBRepository(&mockDynamoDBClientError{}),
+	}
+	event := events.DynamoDBEvent{
+		Records: []events.DynamoDBEventRecord{
+			{EventID: "1", EventName: "INSERT", Change: events.DynamoDBStreamRecord{SequenceNumber: "100", NewImage: NewImage("Mutant")}},
+			{EventID: "2", EventName: "INSERT", Change: events.DynamoDBStreamRecord{SequenceNumber: "101", NewImage: NewImage("Mutant")}},
+		},
+	}
+	response, _ := d.HandleStream(event)
+	if len(response.BatchItemFailures) != 1 || response.BatchItemFailures[0].ItemIdentifier != "100" {
+		t.Error("Expected the first failing record to be reported. Got:", response.BatchItemFailures)
+	}
+}
+
+func TestProcessModifyUpdatesEachItemOnce(t *testing.T) {
+	db := &mockDynamoDBClient{}
+	repo := repository.NewDynamoDBRepository(db)
+	repo.StatShards = 4
+	d := dependencies{
+		repo: repo,
+	}
+	record := events.DynamoDBEventRecord{
+		EventID:   "1",
+		EventName: "MODIFY",
+		Change: events.DynamoDBStreamRecord{
+			OldImage: NewImage("Human", "ATGC", "CAGT", "TTAT", "AGAC"),
+			NewImage: NewImage("Mutant", "ATGC", "CAGT", "TTAT", "AGAC"),
+		},
+	}
+	err := d.ProcessRecord(record)
+	if err != nil || len(db.transactions) != 1 {
+		t.Fatal("Expected a transaction. Got:", err)
+	}
+	keys := map[string]bool{}
+	for _, item := range db.transactions[0].TransactItems {
+		table, key := "", map[string]*dynamodb.AttributeValue{}
+		if item.Put != nil {
+			table, key = *item.Put.TableName, item.Put.Item
+		} else {
+			table, key = *item.Update.TableName, item.Update.Key
+		}
+		for name, value := range key {
+			if value.S == nil {
+				continue
+			}
+			id := table + "/" + name + "/" + *value.S
+			if keys[id] {
+				t.Error("Expected a single operation on the item. Got it twice:", id)
+			}
+			keys[id] = true
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DynamoDB makes a client span of every read and write of the client, as a
+// child of the span in its context. The calls of the client do not take a
+// context, so each request or invocation uses a copy with its own
+type DynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	ctx context.Context
+}
+
+func NewDynamoDB(db dynamodbiface.DynamoDBAPI) *DynamoDB {
+	return &DynamoDB{
+		DynamoDBAPI: db,
+		ctx:         context.Background(),
+	}
+}
+
+// WithContext returns a copy of the client whose spans are children of the
+// span of the context
+func (d *DynamoDB) WithContext(ctx context.Context) *DynamoDB {
+	return &DynamoDB{
+		DynamoDBAPI: d.DynamoDBAPI,
+		ctx:         ctx,
+	}
+}
+
+// WithContext binds the client to the context when it is traced, and returns
+// any other one as is
+func WithContext(db dynamodbiface.DynamoDBAPI, ctx context.Context) dynamodbiface.DynamoDBAPI {
+	if traced, ok := db.(*DynamoDB); ok {
+		return traced.WithContext(ctx)
+	}
+	return db
+}
+
+func (d *DynamoDB) Start(operation string, tables ...string) trace.Span {
+	_, span := Tracer().Start(d.ctx, "DynamoDB."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "dynamodb"),
+			attribute.String("db.operation", operation),
+			attribute.StringSlice("aws.dynamodb.table_names", tables),
+		),
+	)
+	return span
+}
+
+func (d *DynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	span := d.Start("GetItem", aws.StringValue(input.TableName))
+	output, err := d.DynamoDBAPI.GetItem(input)
+	End(span, err)
+	return output, err
+}
+
+func (d *DynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	span := d.Start("PutItem", aws.StringValue(input.TableName))
+	output, err := d.DynamoDBAPI.PutItem(input)
+	End(span, err)
+	return output, err
+}
+
+func (d *DynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	span := d.Start("UpdateItem", aws.StringValue(input.TableName))
+	output, err := d.DynamoDBAPI.UpdateItem(input)
+	End(span, err)
+	return output, err
+}
+
+func (d *DynamoDB) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	span := d.Start("DeleteItem", aws.StringValue(input.TableName))
+	output, err := d.DynamoDBAPI.DeleteItem(input)
+	End(span, err)
+	return output, err
+}
+
+func (d *DynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	span := d.Start("Query", aws.StringValue(input.TableName))
+	output, err := d.DynamoDBAPI.Query(input)
+	End(span, err)
+	return output, err
+}
+
+// ScanPages is a single span for every page of the scan
+func (d *DynamoDB) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	span := d.Start("Scan", aws.StringValue(input.TableName))
+	err := d.DynamoDBAPI.ScanPages(input, fn)
+	End(span, err)
+	return err
+}
+
+func (d *DynamoDB) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	tables := []string{}
+	for table := range input.RequestItems {
+		tables = append(tables, table)
+	}
+	span := d.Start("BatchGetItem", tables...)
+	output, err := d.DynamoDBAPI.BatchGetItem(input)
+	End(span, err)
+	return output, err
+}
+
+// TransactWriteItems is the write of a dna with its counters
+func (d *DynamoDB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	span := d.Start("TransactWriteItems", TransactionTables(input)...)
+	output, err := d.DynamoDBAPI.TransactWriteItems(input)
+	End(span, err)
+	return output, err
+}
+
+func TransactionTables(input *dynamodb.TransactWriteItemsInput) []string {
+	tables := []string{}
+	seen := map[string]bool{}
+	for _, item := range input.TransactItems {
+		table := ""
+		switch {
+		case item.Put != nil:
+			table = aws.StringValue(item.Put.TableName)
+		case item.Update != nil:
+			table = aws.StringValue(item.Update.TableName)
+		case item.Delete != nil:
+			table = aws.StringValue(item.Delete.TableName)
+		case item.ConditionCheck != nil:
+			table = aws.StringValue(item.ConditionCheck.TableName)
+		}
+		if table != "" && !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// Headers carries the trace context in the headers of a request. The keys of
+// the propagator are lowercase, so the headers are matched in any case
+type Headers map[string]string
+
+func (h Headers) Get(key string) string {
+	for name, value := range h {
+		if strings.EqualFold(name, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+func (h Headers) Set(key string, value string) {
+	for name := range h {
+		if strings.EqualFold(name, key) {
+			delete(h, name)
+		}
+	}
+	h[key] = value
+}
+
+func (h Headers) Keys() []string {
+	keys := []string{}
+	for name := range h {
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+// MessageAttributes carries the trace context in the attributes of a message
+// to publish
+type MessageAttributes map[string]*sns.MessageAttributeValue
+
+func (a MessageAttributes) Get(key string) string {
+	if value, ok := a[key]; ok {
+		return aws.StringValue(value.StringValue)
+	}
+	return ""
+}
+
+func (a MessageAttributes) Set(key string, value string) {
+	a[key] = &sns.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+}
+
+func (a MessageAttributes) Keys() []string {
+	keys := []string{}
+	for key := range a {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// EventAttributes carries the trace context in the attributes of a delivered
+// message, which SNS sends as {"Type": ..., "Value": ...} objects
+type EventAttributes map[string]interface{}
+
+func (a EventAttributes) Get(key string) string {
+	attribute, ok := a[key].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	value, _ := attribute["Value"].(string)
+	return value
+}
+
+func (a EventAttributes) Set(key string, value string) {
+	a[key] = map[string]interface{}{
+		"Type":  "String",
+		"Value": value,
+	}
+}
+
+func (a EventAttributes) Keys() []string {
+	keys := []string{}
+	for key := range a {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// SQSAttributes carries the trace context in the attributes of a queue
+// message, which has the ones of the notification with raw message delivery
+type SQSAttributes map[string]events.SQSMessageAttribute
+
+func (a SQSAttributes) Get(key string) string {
+	if value, ok := a[key]; ok {
+		return aws.StringValue(value.StringValue)
+	}
+	return ""
+}
+
+func (a SQSAttributes) Set(key string, value string) {
+	a[key] = events.SQSMessageAttribute{
+		DataType:    "String",
+		StringValue: aws.String(value),
+	}
+}
+
+func (a SQSAttributes) Keys() []string {
+	keys := []string{}
+	for key := range a {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Inject adds the trace context to the headers, which can be nil
+func Inject(ctx context.Context, headers map[string]string) map[string]string {
+	injected := Headers{}
+	for name, value := range headers {
+		injected[name] = value
+	}
+	Propagator().Inject(ctx, injected)
+	return injected
+}
+
+func Extract(headers map[string]string) context.Context {
+	return Propagator().Extract(context.Background(), Headers(headers))
+}
+
+// InjectAttributes adds the trace context to the attributes of the message
+func InjectAttributes(ctx context.Context, input *sns.PublishInput) {
+	if input.MessageAttributes == nil {
+		input.MessageAttributes = map[string]*sns.MessageAttributeValue{}
+	}
+	Propagator().Inject(ctx, MessageAttributes(input.MessageAttributes))
+}
+
+func ExtractEvent(attributes map[string]interface{}) context.Context {
+	return Propagator().Extract(context.Background(), EventAttributes(attributes))
+}
+
+func ExtractSQS(attributes map[string]events.SQSMessageAttribute) context.Context {
+	return Propagator().Extract(context.Background(), SQSAttributes(attributes))
+}
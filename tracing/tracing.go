@@ -0,0 +1,126 @@
+// Package tracing follows a dna with OpenTelemetry spans from its analysis to
+// its saving: the trace context goes from the request headers to the SNS
+// message attributes, and the DynamoDB calls are spans of the invocation
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TRACER_NAME = "github.com/fpinatares/magneto"
+
+const DEFAULT_SERVICE_NAME = "magneto"
+
+// The exporters of OTEL_TRACES_EXPORTER. Console is the name the OpenTelemetry
+// specification gives to stdout
+const (
+	EXPORTER_NONE    = "none"
+	EXPORTER_OTLP    = "otlp"
+	EXPORTER_STDOUT  = "stdout"
+	EXPORTER_CONSOLE = "console"
+)
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(TRACER_NAME)
+}
+
+// Propagator reads and writes the W3C traceparent and baggage
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// Setup exports the spans to the exporter in OTEL_TRACES_EXPORTER. Without
+// one, the spans are not recorded, but the trace context of the requests is
+// still passed on
+func Setup() {
+	otel.SetTextMapPropagator(Propagator())
+	provider, err := NewProvider(context.Background(), os.Getenv("OTEL_TRACES_EXPORTER"), os.Stdout)
+	if err != nil {
+		log.Printf("Got error starting the tracer: %s", err)
+		return
+	}
+	if provider != nil {
+		otel.SetTracerProvider(provider)
+	}
+}
+
+// NewProvider is nil for the none exporter. The OTLP one sends the spans over
+// HTTP to OTEL_EXPORTER_OTLP_ENDPOINT in batches, and the stdout one writes
+// each span to out as JSON when it ends
+func NewProvider(ctx context.Context, exporter string, out io.Writer) (*sdktrace.TracerProvider, error) {
+	options := []sdktrace.TracerProviderOption{}
+	switch strings.ToLower(exporter) {
+	case "", EXPORTER_NONE:
+		return nil, nil
+	case EXPORTER_OTLP:
+		otlp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(otlp))
+	case EXPORTER_STDOUT, EXPORTER_CONSOLE:
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(out))
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithSyncer(stdout))
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %s", exporter)
+	}
+	res, err := NewResource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, sdktrace.WithResource(res))
+	return sdktrace.NewTracerProvider(options...), nil
+}
+
+// NewResource names the service after the lambda function, unless
+// OTEL_SERVICE_NAME or OTEL_RESOURCE_ATTRIBUTES name it
+func NewResource(ctx context.Context) (*resource.Resource, error) {
+	name := os.Getenv("AWS_LAMBDA_FUNCTION_NAME")
+	if name == "" {
+		name = DEFAULT_SERVICE_NAME
+	}
+	return resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", name)),
+		resource.WithFromEnv(),
+	)
+}
+
+// Flush exports the spans of the batch before the lambda is frozen at the end
+// of the invocation
+func Flush(ctx context.Context) {
+	provider, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	if !ok {
+		return
+	}
+	err := provider.ForceFlush(ctx)
+	if err != nil {
+		log.Printf("Got error flushing spans: %s", err)
+	}
+}
+
+// End records the error in the span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
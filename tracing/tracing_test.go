@@ -0,0 +1,152 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const TRACEPARENT = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func NewTestRecorder() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func ResetProvider() {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+}
+
+type mockDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m *mockDynamoDBClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return nil, errors.New("Put item error")
+}
+
+func (m *mockDynamoDBClient) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func TestExtractHeadersInAnyCase(t *testing.T) {
+	ctx := Extract(map[string]string{"Traceparent": TRACEPARENT})
+	spanContext := trace.SpanContextFromContext(ctx)
+	if spanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Error("Expected the trace of the header. Got:", spanContext.TraceID())
+	}
+}
+
+func TestInjectKeepsHeaders(t *testing.T) {
+	ctx := Extract(map[string]string{"traceparent": TRACEPARENT})
+	headers := map[string]string{"Content-Type": "application/json"}
+	injected := Inject(ctx, headers)
+	if injected["Content-Type"] != "application/json" || injected["traceparent"] != TRACEPARENT {
+		t.Error("Expected the headers with the trace context. Got:", injected)
+	}
+	if _, ok := headers["traceparent"]; ok {
+		t.Error("Expected the headers of the request to be left as they were")
+	}
+}
+
+func TestTraceContextGoesThroughMessageAttributes(t *testing.T) {
+	ctx := Extract(map[string]string{"traceparent": TRACEPARENT})
+	input := &sns.PublishInput{Message: aws.String("{}")}
+	InjectAttributes(ctx, input)
+	attribute := input.MessageAttributes["traceparent"]
+	if attribute == nil || aws.StringValue(attribute.StringValue) != TRACEPARENT {
+		t.Error("Expected the traceparent attribute. Got:", input.MessageAttributes)
+	}
+	delivered := EventAttributes{}
+	for name, value := range input.MessageAttributes {
+		delivered.Set(name, aws.StringValue(value.StringValue))
+	}
+	spanContext := trace.SpanContextFromContext(ExtractEvent(delivered))
+	if spanContext.TraceID() != trace.SpanContextFromContext(ctx).TraceID() {
+		t.Error("Expected the trace of the published message. Got:", spanContext.TraceID())
+	}
+}
+
+func TestExtractSQSAttributes(t *testing.T) {
+	attributes := SQSAttributes{}
+	attributes.Set("traceparent", TRACEPARENT)
+	spanContext := trace.SpanContextFromContext(ExtractSQS(attributes))
+	if !spanContext.IsRemote() || spanContext.SpanID().String() != "00f067aa0ba902b7" {
+		t.Error("Expected the span of the attribute. Got:", spanContext.SpanID())
+	}
+}
+
+func TestDynamoDBSpansAreChildrenOfTheInvocation(t *testing.T) {
+	recorder := NewTestRecorder()
+	defer ResetProvider()
+	ctx, parent := Tracer().Start(context.Background(), "save-dna process")
+	db := NewDynamoDB(&mockDynamoDBClient{}).WithContext(ctx)
+	db.UpdateItem(&dynamodb.UpdateItemInput{TableName: aws.String("stats")})
+	db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("dnas")})
+	parent.End()
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatal("Expected a span per call and the parent. Got:", len(spans))
+	}
+	if spans[0].Name() != "DynamoDB.UpdateItem" || spans[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Expected the update as a child of the invocation. Got:", spans[0].Name(), spans[0].Parent())
+	}
+	if spans[1].Name() != "DynamoDB.PutItem" || spans[1].Status().Code != codes.Error {
+		t.Error("Expected the put to fail. Got:", spans[1].Name(), spans[1].Status())
+	}
+}
+
+func TestTransactionTables(t *testing.T) {
+	tables := TransactionTables(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{TableName: aws.String("dnas")}},
+			{Update: &dynamodb.Update{TableName: aws.String("stats")}},
+			{Update: &dynamodb.Update{TableName: aws.String("stats")}},
+		},
+	})
+	if strings.Join(tables, ",") != "dnas,stats" {
+		t.Error("Expected each table once. Got:", tables)
+	}
+}
+
+func TestStdoutProviderWritesSpans(t *testing.T) {
+	out := &bytes.Buffer{}
+	provider, err := NewProvider(context.Background(), EXPORTER_STDOUT, out)
+	if err != nil {
+		t.Fatal("No error expected", err)
+	}
+	_, span := provider.Tracer(TRACER_NAME).Start(context.Background(), "detect")
+	span.End()
+	provider.Shutdown(context.Background())
+	if !strings.Contains(out.String(), `"Name":"detect"`) {
+		t.Error("Expected the span as JSON. Got:", out.String())
+	}
+}
+
+func TestNoneProvider(t *testing.T) {
+	provider, err := NewProvider(context.Background(), EXPORTER_NONE, nil)
+	if provider != nil || err != nil {
+		t.Error("Expected no provider. Got:", provider, err)
+	}
+}
+
+func TestUnknownExporter(t *testing.T) {
+	_, err := NewProvider(context.Background(), "zipkin", nil)
+	if err == nil {
+		t.Error("Expected error for an unknown exporter")
+	}
+}